@@ -0,0 +1,134 @@
+// Package cardbox is a Leitner-style spaced repetition engine, like
+// Whitley's cardbox in Zyzzyva. Every user has a cardbox per lexicon.
+// Each alphagram in it sits in a box; answering it correctly moves it
+// up a box, so it comes back after a longer wait, and missing it sends
+// it back to the first box.
+package cardbox
+
+import (
+	"sort"
+	"time"
+
+	"github.com/domino14/gosports/lexicon"
+	"github.com/domino14/gosports/wordwalls"
+)
+
+const day = 24 * time.Hour
+
+// Card is an alphagram in a cardbox.
+type Card struct {
+	Alphagram string    `json:"alphagram"`
+	Box       int       `json:"box"`
+	Due       time.Time `json:"due"`
+	Correct   int       `json:"correct"`
+	Incorrect int       `json:"incorrect"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// Cardbox is one user's cards for one lexicon, by alphagram.
+type Cardbox struct {
+	User    string           `json:"user"`
+	Lexicon string           `json:"lexicon"`
+	Cards   map[string]*Card `json:"cards"`
+}
+
+func New(user string, lexicon string) *Cardbox {
+	return &Cardbox{User: user, Lexicon: lexicon,
+		Cards: make(map[string]*Card)}
+}
+
+// Schedule is how long a card waits in each box before it is due
+// again. A card can't go past the last box.
+type Schedule struct {
+	Intervals []time.Duration
+}
+
+// DefaultSchedule waits 1, 3, 7, 14, 30, 60 and then 120 days.
+var DefaultSchedule = Schedule{Intervals: []time.Duration{
+	1 * day, 3 * day, 7 * day, 14 * day, 30 * day, 60 * day, 120 * day,
+}}
+
+// Answer moves the card for a correct or incorrect answer, and works
+// out when it is due next.
+func (s Schedule) Answer(c *Card, correct bool, now time.Time) {
+	if correct {
+		c.Correct++
+		if c.Box < len(s.Intervals)-1 {
+			c.Box++
+		}
+	} else {
+		c.Incorrect++
+		c.Box = 0
+	}
+	c.LastSeen = now
+	c.Due = now.Add(s.Intervals[c.Box])
+}
+
+// Add puts alphagrams into the first box, due now. Alphagrams that are
+// already in the cardbox stay where they are.
+func (b *Cardbox) Add(alphagrams []string, now time.Time) {
+	for _, alpha := range alphagrams {
+		alpha = lexicon.Alphagram(alpha)
+		if _, ok := b.Cards[alpha]; !ok {
+			b.Cards[alpha] = &Card{Alphagram: alpha, Due: now}
+		}
+	}
+}
+
+type byDue []*Card
+
+func (c byDue) Len() int      { return len(c) }
+func (c byDue) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c byDue) Less(i, j int) bool {
+	if !c[i].Due.Equal(c[j].Due) {
+		return c[i].Due.Before(c[j].Due)
+	}
+	return c[i].Alphagram < c[j].Alphagram
+}
+
+// Due returns up to max cards that are due at now, the most overdue
+// first. If max is 0, all of the due cards are returned.
+func (b *Cardbox) Due(now time.Time, max int) []*Card {
+	due := []*Card{}
+	for _, c := range b.Cards {
+		if !c.Due.After(now) {
+			due = append(due, c)
+		}
+	}
+	sort.Sort(byDue(due))
+	if max > 0 && max < len(due) {
+		due = due[:max]
+	}
+	return due
+}
+
+// BoxCounts is how many cards are in each box.
+func (b *Cardbox) BoxCounts(s Schedule) []int {
+	counts := make([]int, len(s.Intervals))
+	for _, c := range b.Cards {
+		if c.Box < len(counts) {
+			counts[c.Box]++
+		}
+	}
+	return counts
+}
+
+// Quiz makes the questions for the cards that are due, with their
+// answers from the lexicon. Cards with no answers in the lexicon are
+// left out.
+func (b *Cardbox) Quiz(lex *lexicon.Lexicon, now time.Time,
+	max int) []wordwalls.Question {
+	questions := []wordwalls.Question{}
+	for _, c := range b.Due(now, max) {
+		answers := lex.Anagrams(c.Alphagram)
+		if len(answers) == 0 {
+			continue
+		}
+		questions = append(questions, wordwalls.Question{
+			Question:    c.Alphagram,
+			Answers:     answers,
+			Probability: lex.Probability(c.Alphagram),
+		})
+	}
+	return questions
+}
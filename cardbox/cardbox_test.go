@@ -0,0 +1,99 @@
+package cardbox
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/domino14/gosports/lexicon"
+	"github.com/domino14/gosports/wordwalls"
+)
+
+func TestSchedule(t *testing.T) {
+	now := time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := Schedule{Intervals: []time.Duration{day, 4 * day}}
+	c := &Card{Alphagram: "AEINRST"}
+	s.Answer(c, true, now)
+	if c.Box != 1 || !c.Due.Equal(now.Add(4*day)) {
+		t.Errorf("Unexpected card %v", c)
+	}
+	// It can't go past the last box.
+	s.Answer(c, true, now)
+	if c.Box != 1 || c.Correct != 2 {
+		t.Errorf("Unexpected card %v", c)
+	}
+	s.Answer(c, false, now)
+	if c.Box != 0 || !c.Due.Equal(now.Add(day)) || c.Incorrect != 1 {
+		t.Errorf("Unexpected card %v", c)
+	}
+}
+
+func TestQuiz(t *testing.T) {
+	now := time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)
+	lex := lexicon.New("Test", []string{"RETAINS", "STAINER", "QI", "ZA"})
+	b := New("cesar", "Test")
+	b.Add([]string{"retains", "QI", "AZ", "XYZ"}, now)
+	b.Cards["IQ"].Due = now.Add(-day)
+	b.Cards["AZ"].Due = now.Add(day)
+	quiz := b.Quiz(lex, now, 0)
+	// XYZ has no answers, and AZ isn't due yet.
+	if len(quiz) != 2 || quiz[0].Question != "IQ" ||
+		quiz[1].Question != "AEINRST" || len(quiz[1].Answers) != 2 {
+		t.Errorf("Unexpected quiz %v", quiz)
+	}
+	if quiz := b.Quiz(lex, now, 1); len(quiz) != 1 {
+		t.Errorf("Should have been limited to 1 question, got %v", quiz)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	store, _ := NewFileStore("")
+	now := time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)
+	store.Update("cesar", "Test", func(b *Cardbox) {
+		b.Add([]string{"AEINRST", "IQ", "AZ"}, now)
+	})
+	r := Recorder{Store: store, Schedule: DefaultSchedule}
+	r.RecordRound(&wordwalls.RoundResult{
+		Lexicon: "Test",
+		Ended:   now,
+		Questions: []wordwalls.Question{
+			{Question: "AEINRST"}, {Question: "IQ"}, {Question: "AZ"},
+			{Question: "ADEINRT"},
+		},
+		Players: []string{"cesar"},
+		Solved:  map[string][]string{"cesar": {"STAINER", "QI"}},
+		Missed:  map[string][]string{"cesar": {"IQ", "ADEINRT"}},
+	})
+	b, _ := store.Get("cesar", "Test")
+	// IQ had an answer left, even though cesar found QI.
+	if b.Cards["AEINRST"].Box != 1 || b.Cards["IQ"].Box != 0 ||
+		b.Cards["IQ"].Incorrect != 1 || b.Cards["AZ"].Box != 0 ||
+		b.Cards["AZ"].LastSeen != (time.Time{}) {
+		t.Errorf("Unexpected cards %v %v %v", b.Cards["AEINRST"],
+			b.Cards["IQ"], b.Cards["AZ"])
+	}
+	// Missed alphagrams are added to the cardbox.
+	if c, ok := b.Cards["ADEINRT"]; !ok || c.Box != 0 || c.Incorrect != 1 {
+		t.Errorf("Missed alphagram should have been added, got %v", c)
+	}
+	if counts := b.BoxCounts(DefaultSchedule); counts[0] != 3 || counts[1] != 1 {
+		t.Errorf("Unexpected box counts %v", counts)
+	}
+}
+
+func TestHandlerUnauthorized(t *testing.T) {
+	store, _ := NewFileStore("")
+	h := Handler{Store: store, Schedule: DefaultSchedule,
+		Verify: func(v url.Values, now int64) (string, error) {
+			return "", errors.New("token signature was not correct")
+		}}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/cardbox/?lexicon=x", nil))
+	if rec.Code != 401 ||
+		strings.TrimSpace(rec.Body.String()) != "Unauthorized" {
+		t.Errorf("Expected a plain 401, got %d %s", rec.Code, rec.Body)
+	}
+}
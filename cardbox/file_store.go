@@ -0,0 +1,101 @@
+package cardbox
+
+import (
+	"sync"
+
+	"github.com/domino14/gosports/jsonfile"
+)
+
+// Store keeps cardboxes.
+type Store interface {
+	// Get returns a copy of a user's cardbox, which is empty if they
+	// don't have one yet.
+	Get(user string, lexicon string) (*Cardbox, error)
+	// Update changes a user's cardbox, creating it if needed. No one
+	// else can change the cardbox while update runs.
+	Update(user string, lexicon string, update func(b *Cardbox)) error
+	// UpdateAll is Update for several users' cardboxes at once.
+	UpdateAll(users []string, lexicon string, update func(b *Cardbox)) error
+}
+
+// FileStore is a Store that saves every user's cardboxes, for all
+// lexica, in one JSON file (see the jsonfile package).
+type FileStore struct {
+	sync.RWMutex
+	path  string
+	boxes map[string]*Cardbox
+}
+
+func boxKey(user string, lexicon string) string {
+	return user + ":" + lexicon
+}
+
+// NewFileStore makes a store backed by the file at path, loading any
+// cardboxes that are already in it.
+func NewFileStore(path string) (*FileStore, error) {
+	fs := &FileStore{path: path, boxes: make(map[string]*Cardbox)}
+	saved := []*Cardbox{}
+	err := jsonfile.Load(path, &saved)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range saved {
+		fs.boxes[boxKey(b.User, b.Lexicon)] = b
+	}
+	return fs, nil
+}
+
+// save writes the store out to its file. The caller must hold the lock.
+func (fs *FileStore) save() error {
+	if fs.path == "" {
+		return nil
+	}
+	saved := []*Cardbox{}
+	for _, b := range fs.boxes {
+		saved = append(saved, b)
+	}
+	return jsonfile.Save(fs.path, saved)
+}
+
+func (fs *FileStore) Get(user string, lexicon string) (*Cardbox, error) {
+	fs.RLock()
+	defer fs.RUnlock()
+	b := New(user, lexicon)
+	if stored, ok := fs.boxes[boxKey(user, lexicon)]; ok {
+		for alpha, c := range stored.Cards {
+			card := *c
+			b.Cards[alpha] = &card
+		}
+	}
+	return b, nil
+}
+
+func (fs *FileStore) Update(user string, lexicon string,
+	update func(b *Cardbox)) error {
+	fs.Lock()
+	defer fs.Unlock()
+	key := boxKey(user, lexicon)
+	b, ok := fs.boxes[key]
+	if !ok {
+		b = New(user, lexicon)
+		fs.boxes[key] = b
+	}
+	update(b)
+	return fs.save()
+}
+
+func (fs *FileStore) UpdateAll(users []string, lexicon string,
+	update func(b *Cardbox)) error {
+	fs.Lock()
+	defer fs.Unlock()
+	for _, user := range users {
+		key := boxKey(user, lexicon)
+		b, ok := fs.boxes[key]
+		if !ok {
+			b = New(user, lexicon)
+			fs.boxes[key] = b
+		}
+		update(b)
+	}
+	return fs.save()
+}
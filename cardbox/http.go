@@ -0,0 +1,117 @@
+package cardbox
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/domino14/gosports/channels"
+	"github.com/domino14/gosports/lexicon"
+	"github.com/domino14/gosports/wordwalls"
+)
+
+// Handler serves a user's cardboxes. It is mounted at /cardbox/, and
+// every request has to be signed (see channels.VerifyUser).
+//
+//	GET  /cardbox/?lexicon=<lex>              how many cards are in each box
+//	GET  /cardbox/quiz?lexicon=<lex>&max=<n>  a word list of the due cards
+//	POST /cardbox/add?lexicon=<lex>           add alphagrams, one per line
+//	POST /cardbox/answer?lexicon=<lex>        {"alphagram": <a>, "correct": <b>}
+type Handler struct {
+	Store    Store
+	Schedule Schedule
+	Verify   channels.Verifier
+}
+
+// Summary describes a cardbox.
+type Summary struct {
+	Lexicon string `json:"lexicon"`
+	Boxes   []int  `json:"boxes"`
+	Due     int    `json:"due"`
+}
+
+type answerRequest struct {
+	Alphagram string `json:"alphagram"`
+	Correct   bool   `json:"correct"`
+}
+
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := channels.RequestUser(w, r, h.Verify)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	lex, ok := lexicon.Get(r.URL.Query().Get("lexicon"))
+	if !ok {
+		http.Error(w, "Unknown lexicon", 400)
+		return
+	}
+	action := strings.Trim(strings.TrimPrefix(r.URL.Path, "/cardbox"), "/")
+	var resp interface{}
+	var err error
+	switch {
+	case action == "" && r.Method == "GET":
+		var b *Cardbox
+		b, err = h.Store.Get(user, lex.Name)
+		if err != nil {
+			break
+		}
+		resp = Summary{Lexicon: lex.Name, Boxes: b.BoxCounts(h.Schedule),
+			Due: len(b.Due(now, 0))}
+	case action == "quiz" && r.Method == "GET":
+		var b *Cardbox
+		b, err = h.Store.Get(user, lex.Name)
+		if err != nil {
+			break
+		}
+		max, _ := strconv.Atoi(r.URL.Query().Get("max"))
+		resp = wordwalls.NewWordList(lex.Name, "Cardbox quiz",
+			b.Quiz(lex, now, max))
+	case action == "add" && r.Method == "POST":
+		alphas, problems, verr := lexicon.Validate(r.Body)
+		if verr != nil || len(problems) > 0 {
+			http.Error(w, "Bad alphagrams", 400)
+			return
+		}
+		err = h.Store.Update(user, lex.Name, func(b *Cardbox) {
+			b.Add(alphas, now)
+		})
+		resp = struct{}{}
+	case action == "answer" && r.Method == "POST":
+		req := answerRequest{}
+		if json.NewDecoder(r.Body).Decode(&req) != nil {
+			http.Error(w, "Bad request", 400)
+			return
+		}
+		alpha := lexicon.Alphagram(req.Alphagram)
+		found := false
+		err = h.Store.Update(user, lex.Name, func(b *Cardbox) {
+			if c, ok := b.Cards[alpha]; ok {
+				found = true
+				h.Schedule.Answer(c, req.Correct, now)
+				resp = c
+			}
+		})
+		if err == nil && !found {
+			http.Error(w, "Not found", 404)
+			return
+		}
+	case action == "" || action == "quiz" || action == "add" ||
+		action == "answer":
+		http.Error(w, "Method not allowed", 405)
+		return
+	default:
+		http.Error(w, "Not found", 404)
+		return
+	}
+	if err != nil {
+		log.Println("[ERROR] Cardbox request", err)
+		http.Error(w, "Internal error", 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
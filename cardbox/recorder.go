@@ -0,0 +1,56 @@
+package cardbox
+
+import (
+	"log"
+	"time"
+
+	"github.com/domino14/gosports/lexicon"
+	"github.com/domino14/gosports/wordwalls"
+)
+
+// Recorder moves cards around based on how players did in wordwalls
+// rounds. For each player, an alphagram that still had answers left on
+// their wall was missed, and goes back to the first box (or into the
+// cardbox, if it wasn't there). An alphagram that was cleared, and
+// that the player found at least one answer to, was answered
+// correctly. Alphagrams that other players cleared don't move.
+type Recorder struct {
+	Store    Store
+	Schedule Schedule
+}
+
+func (r Recorder) RecordRound(result *wordwalls.RoundResult) {
+	err := r.Store.UpdateAll(result.Players, result.Lexicon,
+		func(b *Cardbox) {
+			missed := make(map[string]bool)
+			for _, alpha := range result.Missed[b.User] {
+				missed[alpha] = true
+			}
+			found := make(map[string]bool)
+			for _, word := range result.Solved[b.User] {
+				found[lexicon.Alphagram(word)] = true
+			}
+			r.record(b, result.Questions, missed, found, result.Ended)
+		})
+	if err != nil {
+		log.Println("[ERROR] Updating cardboxes", err)
+	}
+}
+
+func (r Recorder) record(b *Cardbox, questions []wordwalls.Question,
+	missed map[string]bool, found map[string]bool, now time.Time) {
+	for _, q := range questions {
+		alpha := lexicon.Alphagram(q.Question)
+		c, inBox := b.Cards[alpha]
+		switch {
+		case missed[alpha]:
+			if !inBox {
+				b.Add([]string{alpha}, now)
+				c = b.Cards[alpha]
+			}
+			r.Schedule.Answer(c, false, now)
+		case found[alpha] && inBox:
+			r.Schedule.Answer(c, true, now)
+		}
+	}
+}
@@ -91,6 +91,7 @@ func (s *subscription) readPump() {
 		// username.
 		// XXX: we'll end up unmarshalling twice. We should re-think this later.
 		m.realm = s.realm
+		m.connId = s.conn.id
 		m.From = s.conn.username
 		// Remarshal to m.rawdata
 		rawdata, err := json.Marshal(m)
@@ -101,14 +102,25 @@ func (s *subscription) readPump() {
 		m.rawdata = rawdata
 		// Pass it on to the external handler.
 		Hub.handler.HandleMessage(m)
-		// For right now only PrivateMT should not be broadcast.
-		if m.Mtype != PrivateMT {
+		if shouldBroadcast(m) {
 			Hub.broadcast <- m
 		}
 
 	}
 }
 
+// shouldBroadcast says whether a message from a connection is passed on
+// to the rest of its realm.
+func shouldBroadcast(m Message) bool {
+	if m.Mtype == PrivateMT {
+		return false
+	}
+	if f, ok := Hub.handler.(BroadcastFilter); ok {
+		return f.ShouldBroadcast(m)
+	}
+	return true
+}
+
 // write writes a message with the given message type and payload.
 func (s *subscription) write(mt int, payload []byte) error {
 	s.conn.ws.SetWriteDeadline(time.Now().Add(writeWait))
@@ -170,25 +182,82 @@ func validateWsRequest(v url.Values, now int64) error {
 	if user == "" {
 		return fmt.Errorf("no user was specified")
 	}
+	// Reconstruct signed string.
+	ss := fmt.Sprintf("expire=%v&realm=%v&user=%v", timestamp, realm, user)
+	return checkSignature(ss, token, secretKey)
+}
+
+// checkSignature checks that the hex-encoded token is the signature of
+// ss.
+func checkSignature(ss string, token string, secretKey string) error {
 	tokenHex, err := hex.DecodeString(token)
 	if err != nil {
 		return err
 	}
-
-	// Reconstruct signed string.
-	ss := fmt.Sprintf("expire=%v&realm=%v&user=%v", timestamp, realm, user)
 	log.Println("[DEBUG] Signing:", ss)
 	mac := hmac.New(sha1.New, []byte(secretKey))
 	mac.Write([]byte(ss))
 	expectedMac := mac.Sum(nil)
 	if !hmac.Equal(expectedMac, tokenHex) {
-		return fmt.Errorf(
-			"token signature was not correct (got %x, expected %x)",
-			expectedMac, tokenHex)
+		// Never say what the signature should have been; that would
+		// let anyone sign as anyone.
+		return fmt.Errorf("token signature was not correct")
 	}
 	return nil
 }
 
+// VerifyUser checks the signature on a request to one of our HTTP
+// APIs, and returns the user that made it. These are signed like
+// websocket requests, but without a realm, i.e. the token signs
+// "expire=<ts>&user=<user>".
+func VerifyUser(v url.Values, now int64) (string, error) {
+	secretKey := os.Getenv("SECRET_KEY")
+	user := v.Get("user")
+	timestamp := v.Get("expire")
+	if secretKey == "" {
+		log.Println("[ERROR] Secret key missing!")
+		return "", fmt.Errorf("No secret key in environment.")
+	}
+	ts_int, err := strconv.Atoi(timestamp)
+	if err != nil {
+		return "", err
+	}
+	if int64(ts_int) < now {
+		return "", fmt.Errorf("your token has expired (ts = %v, now = %v)",
+			ts_int, now)
+	}
+	if user == "" {
+		return "", fmt.Errorf("no user was specified")
+	}
+	ss := fmt.Sprintf("expire=%v&user=%v", timestamp, user)
+	err = checkSignature(ss, v.Get("_token"), secretKey)
+	if err != nil {
+		return "", err
+	}
+	return user, nil
+}
+
+// Verifier says who made a signed request; see VerifyUser.
+type Verifier func(v url.Values, now int64) (string, error)
+
+// RequestUser returns the user who signed an HTTP request, checking it
+// with verify, or with VerifyUser if verify is nil. If the request
+// isn't signed properly, a 401 is written and ok is false. What was
+// wrong with it is only logged.
+func RequestUser(w http.ResponseWriter, r *http.Request,
+	verify Verifier) (user string, ok bool) {
+	if verify == nil {
+		verify = VerifyUser
+	}
+	user, err := verify(r.URL.Query(), time.Now().Unix())
+	if err != nil {
+		log.Println("[ERROR] Unauthorized request for", r.URL.Path+":", err)
+		http.Error(w, "Unauthorized", 401)
+		return "", false
+	}
+	return user, true
+}
+
 // close connection with an error string.
 func closeMessage(ws *websocket.Conn, errStr string) {
 	// close code 1008 is used for a generic "policy violation" message.
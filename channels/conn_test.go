@@ -3,6 +3,12 @@ package channels
 import "testing"
 import "net/url"
 import "strings"
+import "os"
+import "crypto/hmac"
+import "crypto/sha1"
+import "encoding/hex"
+import "errors"
+import "net/http/httptest"
 
 func TestVerify(t *testing.T) {
 	v := url.Values{}
@@ -72,3 +78,67 @@ func TestVerifyNoRealm(t *testing.T) {
 		t.Error("Should have gotten a realm error")
 	}
 }
+
+func TestVerifyUser(t *testing.T) {
+	key := os.Getenv("SECRET_KEY")
+	defer os.Setenv("SECRET_KEY", key)
+	os.Setenv("SECRET_KEY", "testkey")
+	mac := hmac.New(sha1.New, []byte("testkey"))
+	mac.Write([]byte("expire=1455998487&user=cesar"))
+	v := url.Values{}
+	v.Set("expire", "1455998487")
+	v.Set("user", "cesar")
+	v.Set("_token", hex.EncodeToString(mac.Sum(nil)))
+	user, err := VerifyUser(v, 1455998487-10)
+	if err != nil || user != "cesar" {
+		t.Errorf("Expected cesar, got %v, %v", user, err)
+	}
+	v.Set("user", "messi")
+	_, err = VerifyUser(v, 1455998487-10)
+	if err == nil || !strings.Contains(err.Error(),
+		"signature was not correct") {
+		t.Error("Should have gotten an invalid signature")
+	}
+}
+
+type noGuesses struct{ SocketMessageHandler }
+
+func (noGuesses) ShouldBroadcast(m Message) bool { return m.Mtype != "guess" }
+
+func TestShouldBroadcast(t *testing.T) {
+	handler := Hub.handler
+	defer func() { Hub.handler = handler }()
+	Hub.handler = nil
+	if shouldBroadcast(Message{Mtype: PrivateMT}) ||
+		!shouldBroadcast(Message{Mtype: "guess"}) {
+		t.Errorf("Everything but private messages should be broadcast")
+	}
+	Hub.handler = noGuesses{}
+	if shouldBroadcast(Message{Mtype: "guess"}) ||
+		!shouldBroadcast(Message{Mtype: "chat"}) ||
+		shouldBroadcast(Message{Mtype: PrivateMT}) {
+		t.Errorf("The handler should have kept guesses from the realm")
+	}
+}
+
+func TestRequestUser(t *testing.T) {
+	verify := func(v url.Values, now int64) (string, error) {
+		if v.Get("user") == "" {
+			return "", errors.New("expected signature abc123")
+		}
+		return v.Get("user"), nil
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/lists/?user=cesar", nil)
+	if user, ok := RequestUser(w, r, verify); !ok || user != "cesar" {
+		t.Errorf("Expected cesar, got %v %v", user, ok)
+	}
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/lists/", nil)
+	if _, ok := RequestUser(w, r, verify); ok {
+		t.Errorf("An unsigned request should not have been let through")
+	}
+	if w.Code != 401 || strings.TrimSpace(w.Body.String()) != "Unauthorized" {
+		t.Errorf("Unexpected response %v %q", w.Code, w.Body.String())
+	}
+}
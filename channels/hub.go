@@ -16,6 +16,13 @@ type subscription struct {
 
 type Realm string
 
+// kickRequest asks the hub to close a single connection in a realm.
+type kickRequest struct {
+	realm  Realm
+	connId string
+	reason string
+}
+
 // hub maintains the set of active connections and broadcasts messages to the
 // connections.
 type hub struct {
@@ -25,12 +32,18 @@ type hub struct {
 	// Inbound messages from the connections.
 	broadcast chan Message
 
+	// Messages for a single user in a realm.
+	direct chan Message
+
 	// Register requests from the connections.
 	register chan *subscription
 
 	// Unregister requests from connections.
 	unregister chan *subscription
 
+	// Requests to forcibly close a connection.
+	kick chan kickRequest
+
 	// A handler of messages.
 	handler SocketMessageHandler
 }
@@ -38,8 +51,10 @@ type hub struct {
 // The global, singleton Hub object. This manages all our connections.
 var Hub = hub{
 	broadcast:  make(chan Message),
+	direct:     make(chan Message),
 	register:   make(chan *subscription),
 	unregister: make(chan *subscription),
+	kick:       make(chan kickRequest),
 	realms:     make(map[Realm]map[*connection]bool),
 }
 
@@ -47,6 +62,32 @@ func BroadcastMessage(realm Realm, mt MessageType, msg string) {
 	Hub.broadcastMessage(realm, mt, msg)
 }
 
+// SendMessage sends a message to every connection the given user has
+// open in this realm.
+func SendMessage(realm Realm, mt MessageType, msg string, to string) {
+	msgWrapper := Message{
+		Data:  msg,
+		Mtype: mt,
+		realm: realm,
+		to:    to,
+	}
+	rawdata, err := json.Marshal(msgWrapper)
+	if err != nil {
+		log.Println("[ERROR] JSON encoding - sending message", err)
+		return
+	}
+	msgWrapper.rawdata = rawdata
+	Hub.direct <- msgWrapper
+}
+
+// Disconnect closes the connection with the given id, sending it a
+// KickedMT message with the reason first. Note that the realm handlers
+// are called from within the hub's goroutine, so they must not call
+// this synchronously.
+func Disconnect(realm Realm, connId string, reason string) {
+	Hub.kick <- kickRequest{realm: realm, connId: connId, reason: reason}
+}
+
 func (h *hub) broadcastMessage(realm Realm, mt MessageType, msg string) {
 	var msgWrapper Message
 	msgWrapper = Message{
@@ -97,20 +138,56 @@ func (h *hub) Run(handler SocketMessageHandler) {
 					}
 				}
 			}
+		case k := <-h.kick:
+			connections := h.realms[k.realm]
+			for c := range connections {
+				if c.id != k.connId {
+					continue
+				}
+				rawdata, err := json.Marshal(Message{Data: k.reason,
+					Mtype: KickedMT})
+				if err == nil {
+					select {
+					case c.send <- rawdata:
+					default:
+					}
+				}
+				log.Println("[DEBUG] Kicking", c.username, c.id)
+				close(c.send)
+				delete(connections, c)
+				if len(connections) == 0 {
+					delete(h.realms, k.realm)
+					h.handler.RealmDeletion(k.realm)
+				}
+			}
 		case m := <-h.broadcast:
 			connections := h.realms[m.realm]
 			for c := range connections {
-				select {
-				case c.send <- m.rawdata:
-				default:
-					log.Println("[DEBUG] Disconnecting", c.username)
-					close(c.send)
-					delete(connections, c)
-					if len(connections) == 0 {
-						delete(h.realms, m.realm)
-					}
+				h.send(m.realm, connections, c, m.rawdata)
+			}
+		case m := <-h.direct:
+			connections := h.realms[m.realm]
+			for c := range connections {
+				if c.username == m.to {
+					h.send(m.realm, connections, c, m.rawdata)
 				}
 			}
 		}
 	}
 }
+
+// send queues a message for a connection, dropping the connection if
+// its buffer is full.
+func (h *hub) send(realm Realm, connections map[*connection]bool,
+	c *connection, rawdata []byte) {
+	select {
+	case c.send <- rawdata:
+	default:
+		log.Println("[DEBUG] Disconnecting", c.username)
+		close(c.send)
+		delete(connections, c)
+		if len(connections) == 0 {
+			delete(h.realms, realm)
+		}
+	}
+}
@@ -7,6 +7,9 @@ const (
 
 	// Message types that should not be broadcast.
 	PrivateMT MessageType = "pm"
+
+	// Sent to a connection right before the server closes it.
+	KickedMT MessageType = "kicked"
 )
 
 type Message struct {
@@ -14,6 +17,8 @@ type Message struct {
 	Mtype   MessageType `json:"type"`
 	rawdata []byte
 	realm   Realm  // This will get copied from the subscription.
+	connId  string // So will this.
+	to      string // Set only for messages to a single user.
 	From    string `json:"from"`
 }
 
@@ -25,6 +30,15 @@ func (m *Message) SetRealm(realm Realm) {
 	m.realm = realm
 }
 
+// ConnID is the id of the connection this message came in on.
+func (m *Message) ConnID() string {
+	return m.connId
+}
+
+func (m *Message) SetConnID(connId string) {
+	m.connId = connId
+}
+
 type SocketMessageHandler interface {
 	// HandleMessage must take in a message and perform some sort of
 	// action with it.
@@ -41,6 +55,13 @@ type SocketMessageHandler interface {
 	RealmLeave(realm Realm, user string, connId string)
 }
 
+// A BroadcastFilter is a SocketMessageHandler that decides which of
+// the messages it handled are passed on to everyone in the realm. By
+// default every message but a PrivateMT is.
+type BroadcastFilter interface {
+	ShouldBroadcast(m Message) bool
+}
+
 // SocketMessageSender is an interface that will send messages from our
 // server to one or more websockets.
 type SocketMessageSender interface {
@@ -48,4 +69,6 @@ type SocketMessageSender interface {
 	BroadcastMessage(realm Realm, mt MessageType, msg string)
 	// Send a message to just one single player.
 	SendMessage(realm Realm, mt MessageType, msg string, to string)
+	// Disconnect a single connection, letting it know why first.
+	Disconnect(realm Realm, connId string, reason string)
 }
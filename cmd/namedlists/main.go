@@ -0,0 +1,62 @@
+// Command namedlists writes the standard named lists for a lexicon as
+// JSON word list files, one file per list, in the same format that
+// study mode loads them in. The files are named after the lists, e.g.
+// "America 7s (1-500)" goes in america-7s-1-500.json and is studied
+// with "study america-7s-1-500".
+//
+//	namedlists -lexicon-dir lexica -lexicon America -previous OWL3 -out lists
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/domino14/gosports/lexicon"
+	"github.com/domino14/gosports/namedlist"
+)
+
+var lexiconDir = flag.String("lexicon-dir", "",
+	"directory of word list files to load the lexica from")
+var lexiconName = flag.String("lexicon", "", "lexicon to make lists for")
+var previousName = flag.String("previous", "",
+	"lexicon that this one replaces, to make lists of the new words")
+var outDir = flag.String("out", ".", "directory to write the lists to")
+
+func main() {
+	flag.Parse()
+	if *lexiconDir == "" || *lexiconName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	err := lexicon.LoadDir(*lexiconDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	lex, ok := lexicon.Get(*lexiconName)
+	if !ok {
+		log.Fatalf("lexicon %v is not in %v", *lexiconName, *lexiconDir)
+	}
+	var previous *lexicon.Lexicon
+	if *previousName != "" {
+		previous, ok = lexicon.Get(*previousName)
+		if !ok {
+			log.Fatalf("lexicon %v is not in %v", *previousName, *lexiconDir)
+		}
+	}
+	for _, list := range namedlist.Generate(lex, previous) {
+		body, err := json.Marshal(list)
+		if err != nil {
+			log.Fatal(err)
+		}
+		path := filepath.Join(*outDir, namedlist.FileName(list.Name)+".json")
+		err = ioutil.WriteFile(path, body, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Wrote %v (%d alphagrams)", path, list.NumQuestions)
+	}
+}
@@ -0,0 +1,45 @@
+// Package jsonfile loads and saves the JSON files that the file-backed
+// stores keep their data in. These stores keep everything in memory,
+// and write the whole file out again after every change. A store with
+// an empty path never touches the disk, which is handy for tests.
+package jsonfile
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+)
+
+// Load reads the JSON file at path into v. It does nothing if the path
+// is empty or the file doesn't exist yet.
+func Load(path string, v interface{}) error {
+	if path == "" {
+		return nil
+	}
+	body, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// Save writes v to the file at path as JSON. It writes to a temporary
+// file first, and then moves it into place, so a crash can't leave a
+// half-written file behind. It does nothing if the path is empty.
+func Save(path string, v interface{}) error {
+	if path == "" {
+		return nil
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, body, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
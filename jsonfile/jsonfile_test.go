@@ -0,0 +1,35 @@
+package jsonfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsonfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "counts.json")
+
+	counts := map[string]int{}
+	if err := Load(path, &counts); err != nil || len(counts) != 0 {
+		t.Errorf("Loading a missing file should do nothing, got %v, %v",
+			counts, err)
+	}
+	if err := Save(path, map[string]int{"QI": 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("The temporary file should have been moved, got %v", err)
+	}
+	if err := Load(path, &counts); err != nil || counts["QI"] != 2 {
+		t.Errorf("Expected to load QI: 2, got %v, %v", counts, err)
+	}
+	if err := Save("", counts); err != nil {
+		t.Errorf("Saving with no path should do nothing, got %v", err)
+	}
+}
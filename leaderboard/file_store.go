@@ -0,0 +1,107 @@
+package leaderboard
+
+import (
+	"log"
+	"strconv"
+	"sync"
+
+	"github.com/domino14/gosports/jsonfile"
+)
+
+// FileStore is a LeaderboardStore that saves the entries for every
+// challenge in a JSON file, keyed by challenge ID (see the jsonfile
+// package).
+type FileStore struct {
+	sync.RWMutex
+	path    string
+	entries map[int][]Entry
+}
+
+// NewFileStore makes a store backed by the file at path, loading any
+// entries that are already in it.
+func NewFileStore(path string) (*FileStore, error) {
+	fs := &FileStore{path: path, entries: make(map[int][]Entry)}
+	// JSON object keys have to be strings.
+	saved := make(map[string][]Entry)
+	err := jsonfile.Load(path, &saved)
+	if err != nil {
+		return nil, err
+	}
+	for key, entries := range saved {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, err
+		}
+		fs.entries[id] = entries
+	}
+	return fs, nil
+}
+
+// save writes the store out to its file. The caller must hold the lock.
+func (fs *FileStore) save() error {
+	if fs.path == "" {
+		return nil
+	}
+	saved := make(map[string][]Entry)
+	for id, entries := range fs.entries {
+		saved[strconv.Itoa(id)] = entries
+	}
+	return jsonfile.Save(fs.path, saved)
+}
+
+func (fs *FileStore) Submit(e Entry) (bool, error) {
+	kept, err := fs.SubmitAll([]Entry{e})
+	return len(kept) == 1, err
+}
+
+// hasEntry is true if the user already has an entry for the challenge.
+// The caller must hold the lock.
+func (fs *FileStore) hasEntry(challengeID int, user string) bool {
+	for _, existing := range fs.entries[challengeID] {
+		if existing.User == user {
+			return true
+		}
+	}
+	return false
+}
+
+func (fs *FileStore) SubmitAll(entries []Entry) ([]Entry, error) {
+	fs.Lock()
+	defer fs.Unlock()
+	kept := []Entry{}
+	for _, e := range entries {
+		if fs.hasEntry(e.ChallengeID, e.User) {
+			log.Printf("[DEBUG] %s already has an entry for challenge %d",
+				e.User, e.ChallengeID)
+			continue
+		}
+		fs.entries[e.ChallengeID] = append(fs.entries[e.ChallengeID], e)
+		kept = append(kept, e)
+	}
+	if len(kept) == 0 {
+		return kept, nil
+	}
+	return kept, fs.save()
+}
+
+func (fs *FileStore) Top(challengeID int, n int) ([]RankedEntry, error) {
+	fs.RLock()
+	defer fs.RUnlock()
+	ranked := rank(fs.entries[challengeID])
+	if n > 0 && n < len(ranked) {
+		ranked = ranked[:n]
+	}
+	return ranked, nil
+}
+
+func (fs *FileStore) Rank(challengeID int, user string) (RankedEntry, bool,
+	error) {
+	fs.RLock()
+	defer fs.RUnlock()
+	for _, e := range rank(fs.entries[challengeID]) {
+		if e.User == user {
+			return e, true, nil
+		}
+	}
+	return RankedEntry{}, false, nil
+}
@@ -0,0 +1,90 @@
+package leaderboard
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// Handler serves leaderboards as JSON:
+//
+//	GET ?challenge=<id>&top=<n>    the top n entries
+//	GET ?challenge=<id>&user=<u>   the user's entry
+type Handler struct {
+	Store LeaderboardStore
+}
+
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", 405)
+		return
+	}
+	q := r.URL.Query()
+	challengeID, err := strconv.Atoi(q.Get("challenge"))
+	if err != nil {
+		http.Error(w, "Bad challenge id", 400)
+		return
+	}
+	var resp interface{}
+	if user := q.Get("user"); user != "" {
+		entry, ok, err := h.Store.Rank(challengeID, user)
+		if err != nil {
+			log.Println("[ERROR] Getting rank", err)
+			http.Error(w, "Internal error", 500)
+			return
+		}
+		if !ok {
+			http.Error(w, "Not found", 404)
+			return
+		}
+		resp = entry
+	} else {
+		n, _ := strconv.Atoi(q.Get("top"))
+		entries, err := h.Store.Top(challengeID, n)
+		if err != nil {
+			log.Println("[ERROR] Getting leaderboard", err)
+			http.Error(w, "Internal error", 500)
+			return
+		}
+		resp = entries
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// MissedBingoHandler serves the most missed bingos for a day:
+//
+//	GET ?date=<yyyy-mm-dd>&top=<n>               as JSON
+//	GET ?date=<yyyy-mm-dd>&top=<n>&format=text   as a plain text report
+type MissedBingoHandler struct {
+	Store MissedBingoStore
+}
+
+func (h MissedBingoHandler) ServeHTTP(w http.ResponseWriter,
+	r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", 405)
+		return
+	}
+	q := r.URL.Query()
+	date := q.Get("date")
+	if date == "" {
+		http.Error(w, "No date", 400)
+		return
+	}
+	n, _ := strconv.Atoi(q.Get("top"))
+	missed, err := h.Store.MostMissed(date, n)
+	if err != nil {
+		log.Println("[ERROR] Getting missed bingos", err)
+		http.Error(w, "Internal error", 500)
+		return
+	}
+	if q.Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		WriteReport(w, date, missed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(missed)
+}
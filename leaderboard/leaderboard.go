@@ -0,0 +1,72 @@
+// Package leaderboard keeps the results of daily challenges.
+package leaderboard
+
+import (
+	"sort"
+	"time"
+)
+
+// Entry is one user's result for a challenge.
+type Entry struct {
+	ChallengeID   int       `json:"challengeId"`
+	User          string    `json:"user"`
+	Score         int       `json:"score"`
+	TimeRemaining int       `json:"timeRemaining"`
+	Submitted     time.Time `json:"submitted"`
+}
+
+// RankedEntry is an entry with its place on the leaderboard. Entries
+// with the same score and time remaining share a rank.
+type RankedEntry struct {
+	Entry
+	Rank int `json:"rank"`
+}
+
+// LeaderboardStore keeps the leaderboards for all challenges. Only a
+// user's first result for a challenge counts.
+type LeaderboardStore interface {
+	// Submit an entry. Returns false if the user already had an entry
+	// for this challenge, in which case the new one is thrown away.
+	Submit(e Entry) (bool, error)
+	// SubmitAll is Submit for several entries at once. Returns the
+	// entries that were kept.
+	SubmitAll(entries []Entry) ([]Entry, error)
+	// Top returns the top n entries for a challenge; all of them if n
+	// is not positive.
+	Top(challengeID int, n int) ([]RankedEntry, error)
+	// Rank returns the user's entry for a challenge, and whether they
+	// have one.
+	Rank(challengeID int, user string) (RankedEntry, bool, error)
+}
+
+// Ranks by score, then time remaining. Earlier submissions come first
+// on ties, though they share the rank.
+type byRank []Entry
+
+func (e byRank) Len() int      { return len(e) }
+func (e byRank) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+func (e byRank) Less(i, j int) bool {
+	if e[i].Score != e[j].Score {
+		return e[i].Score > e[j].Score
+	}
+	if e[i].TimeRemaining != e[j].TimeRemaining {
+		return e[i].TimeRemaining > e[j].TimeRemaining
+	}
+	return e[i].Submitted.Before(e[j].Submitted)
+}
+
+// rank sorts a challenge's entries and gives each its rank.
+func rank(entries []Entry) []RankedEntry {
+	sorted := make([]Entry, len(entries))
+	copy(sorted, entries)
+	sort.Sort(byRank(sorted))
+	ranked := make([]RankedEntry, len(sorted))
+	for i, e := range sorted {
+		ranked[i] = RankedEntry{Entry: e, Rank: i + 1}
+		if i > 0 && e.Score == sorted[i-1].Score &&
+			e.TimeRemaining == sorted[i-1].TimeRemaining {
+			ranked[i].Rank = ranked[i-1].Rank
+		}
+	}
+	return ranked
+}
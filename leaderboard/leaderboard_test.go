@@ -0,0 +1,141 @@
+package leaderboard
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func submitAll(t *testing.T, store LeaderboardStore, entries []Entry) {
+	for _, e := range entries {
+		if _, err := store.Submit(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRanking(t *testing.T) {
+	store, _ := NewFileStore("")
+	now := time.Now()
+	submitAll(t, store, []Entry{
+		{ChallengeID: 1, User: "cesar", Score: 40, TimeRemaining: 0,
+			Submitted: now},
+		{ChallengeID: 1, User: "messi", Score: 50, TimeRemaining: 10,
+			Submitted: now},
+		{ChallengeID: 1, User: "xavi", Score: 50, TimeRemaining: 30,
+			Submitted: now},
+		{ChallengeID: 1, User: "iniesta", Score: 40, TimeRemaining: 0,
+			Submitted: now.Add(time.Second)},
+		{ChallengeID: 2, User: "cesar", Score: 10, Submitted: now},
+	})
+	top, _ := store.Top(1, 3)
+	expected := []struct {
+		user string
+		rank int
+	}{{"xavi", 1}, {"messi", 2}, {"cesar", 3}}
+	if len(top) != 3 {
+		t.Fatalf("Should have gotten the top 3, got %v", top)
+	}
+	for i, e := range expected {
+		if top[i].User != e.user || top[i].Rank != e.rank {
+			t.Errorf("Expected %v at rank %v, got %v", e.user, e.rank, top[i])
+		}
+	}
+	// Ties share the rank.
+	mine, ok, _ := store.Rank(1, "iniesta")
+	if !ok || mine.Rank != 3 {
+		t.Errorf("iniesta should have been tied for 3rd, got %v", mine)
+	}
+	if _, ok, _ := store.Rank(2, "messi"); ok {
+		t.Errorf("messi did not play challenge 2")
+	}
+}
+
+func TestOnlyFirstEntryCounts(t *testing.T) {
+	store, _ := NewFileStore("")
+	store.Submit(Entry{ChallengeID: 1, User: "cesar", Score: 10})
+	ok, _ := store.Submit(Entry{ChallengeID: 1, User: "cesar", Score: 50})
+	if ok {
+		t.Errorf("Second entry should have been thrown away")
+	}
+	mine, _, _ := store.Rank(1, "cesar")
+	if mine.Score != 10 {
+		t.Errorf("First entry should have counted, got %v", mine)
+	}
+}
+
+func TestSubmitAll(t *testing.T) {
+	store, _ := NewFileStore("")
+	store.Submit(Entry{ChallengeID: 1, User: "cesar", Score: 10})
+	kept, err := store.SubmitAll([]Entry{
+		{ChallengeID: 1, User: "cesar", Score: 50},
+		{ChallengeID: 1, User: "messi", Score: 20},
+		{ChallengeID: 1, User: "messi", Score: 30},
+	})
+	if err != nil || len(kept) != 1 || kept[0].User != "messi" ||
+		kept[0].Score != 20 {
+		t.Errorf("Only messi's first entry should have been kept, got %v",
+			kept)
+	}
+	top, _ := store.Top(1, 0)
+	if len(top) != 2 || top[0].User != "messi" || top[1].Score != 10 {
+		t.Errorf("Unexpected leaderboard: %v", top)
+	}
+}
+
+func TestFileStorePersists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "leaderboard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "leaderboards.json")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Submit(Entry{ChallengeID: 43643, User: "cesar", Score: 42})
+	reloaded, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mine, ok, _ := reloaded.Rank(43643, "cesar")
+	if !ok || mine.Score != 42 || mine.Rank != 1 {
+		t.Errorf("Entry should have been reloaded, got %v", mine)
+	}
+}
+
+func TestMostMissedBingos(t *testing.T) {
+	store, _ := NewMissedBingoFile("")
+	store.RecordMissed("2026-10-19", "America",
+		[]string{"AEINRST", "ABC", "AEEINRST"})
+	store.RecordMissed("2026-10-19", "America", []string{"AEINRST"})
+	store.RecordMissed("2026-10-19", "CSW15", []string{"AEINRST"})
+	store.RecordMissed("2026-10-20", "America", []string{"AEINRST"})
+	missed, _ := store.MostMissed("2026-10-19", 0)
+	expected := []MissedBingo{
+		{Date: "2026-10-19", Lexicon: "America", Alphagram: "AEINRST",
+			Misses: 2},
+		{Date: "2026-10-19", Lexicon: "America", Alphagram: "AEEINRST",
+			Misses: 1},
+		{Date: "2026-10-19", Lexicon: "CSW15", Alphagram: "AEINRST",
+			Misses: 1},
+	}
+	if len(missed) != len(expected) {
+		t.Fatalf("Unexpected missed bingos: %v", missed)
+	}
+	for i := range expected {
+		if missed[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], missed[i])
+		}
+	}
+	var report bytes.Buffer
+	WriteReport(&report, "2026-10-19", missed[:1])
+	if !strings.Contains(report.String(), "  1. AEINRST    America    2") {
+		t.Errorf("Unexpected report:\n%s", report.String())
+	}
+}
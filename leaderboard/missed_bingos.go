@@ -0,0 +1,139 @@
+package leaderboard
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/domino14/gosports/jsonfile"
+)
+
+// MissedBingo is how many times a bingo was missed in the challenges on
+// a given day, summed over all players.
+type MissedBingo struct {
+	Date      string `json:"date"`
+	Lexicon   string `json:"lexicon"`
+	Alphagram string `json:"alphagram"`
+	Misses    int    `json:"misses"`
+}
+
+// IsBingo is true for the 7- and 8-letter alphagrams that we keep track
+// of.
+func IsBingo(alphagram string) bool {
+	n := len([]rune(alphagram))
+	return n == 7 || n == 8
+}
+
+// MissedBingoStore keeps count of the bingos that players missed in
+// daily challenges.
+type MissedBingoStore interface {
+	// RecordMissed counts one miss for each of these alphagrams, so an
+	// alphagram that several players missed is in there several times.
+	// Any alphagrams that aren't bingos are ignored.
+	RecordMissed(date string, lexicon string, alphagrams []string) error
+	// MostMissed returns the n most missed bingos on this day, across
+	// all lexica; all of them if n is not positive.
+	MostMissed(date string, n int) ([]MissedBingo, error)
+}
+
+// MissedBingoFile is a MissedBingoStore that saves the counts by date,
+// lexicon and alphagram in a JSON file (see the jsonfile package).
+type MissedBingoFile struct {
+	sync.RWMutex
+	path string
+	// date -> lexicon -> alphagram -> misses
+	misses map[string]map[string]map[string]int
+}
+
+// NewMissedBingoFile makes a store backed by the file at path, loading
+// anything that is already in it.
+func NewMissedBingoFile(path string) (*MissedBingoFile, error) {
+	mf := &MissedBingoFile{path: path,
+		misses: make(map[string]map[string]map[string]int)}
+	err := jsonfile.Load(path, &mf.misses)
+	if err != nil {
+		return nil, err
+	}
+	return mf, nil
+}
+
+func (mf *MissedBingoFile) RecordMissed(date string, lexicon string,
+	alphagrams []string) error {
+	mf.Lock()
+	defer mf.Unlock()
+	recorded := false
+	for _, alphagram := range alphagrams {
+		if !IsBingo(alphagram) {
+			continue
+		}
+		byLexicon := mf.misses[date]
+		if byLexicon == nil {
+			byLexicon = make(map[string]map[string]int)
+			mf.misses[date] = byLexicon
+		}
+		counts := byLexicon[lexicon]
+		if counts == nil {
+			counts = make(map[string]int)
+			byLexicon[lexicon] = counts
+		}
+		counts[alphagram]++
+		recorded = true
+	}
+	if !recorded {
+		return nil
+	}
+	return jsonfile.Save(mf.path, mf.misses)
+}
+
+// Most misses first; then by lexicon and alphagram.
+type byMisses []MissedBingo
+
+func (m byMisses) Len() int      { return len(m) }
+func (m byMisses) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
+func (m byMisses) Less(i, j int) bool {
+	if m[i].Misses != m[j].Misses {
+		return m[i].Misses > m[j].Misses
+	}
+	if m[i].Lexicon != m[j].Lexicon {
+		return m[i].Lexicon < m[j].Lexicon
+	}
+	return m[i].Alphagram < m[j].Alphagram
+}
+
+func (mf *MissedBingoFile) MostMissed(date string, n int) ([]MissedBingo,
+	error) {
+	mf.RLock()
+	defer mf.RUnlock()
+	missed := []MissedBingo{}
+	for lexicon, counts := range mf.misses[date] {
+		for alphagram, misses := range counts {
+			missed = append(missed, MissedBingo{Date: date, Lexicon: lexicon,
+				Alphagram: alphagram, Misses: misses})
+		}
+	}
+	sort.Sort(byMisses(missed))
+	if n > 0 && n < len(missed) {
+		missed = missed[:n]
+	}
+	return missed, nil
+}
+
+// WriteReport writes out missed bingos as a plain text report, one
+// bingo per line.
+func WriteReport(w io.Writer, date string, missed []MissedBingo) error {
+	_, err := fmt.Fprintf(w, "Most missed bingos for %s\n%s\n", date,
+		strings.Repeat("-", 40))
+	if err != nil {
+		return err
+	}
+	for i, m := range missed {
+		_, err = fmt.Fprintf(w, "%3d. %-10s %-10s %d\n", i+1, m.Alphagram,
+			m.Lexicon, m.Misses)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
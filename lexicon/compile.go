@@ -0,0 +1,80 @@
+package lexicon
+
+// This file contains the compiled lexicon format. A compiled lexicon is
+// a JSON file with every alphagram and its words, ordered by length
+// and then by probability, so it can be loaded without re-indexing.
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// CompiledExt is the file extension for compiled lexica.
+const CompiledExt = ".lex"
+
+type compiledAlphagram struct {
+	Alphagram   string   `json:"a"`
+	Words       []string `json:"w"`
+	Probability int      `json:"p"`
+}
+
+type compiledLexicon struct {
+	Name       string              `json:"name"`
+	Alphagrams []compiledAlphagram `json:"alphagrams"`
+	// Only words that have definitions are in here.
+	Definitions map[string]string `json:"defs,omitempty"`
+}
+
+// compile works out the probability order of every alphagram, and
+// returns the lexicon in its compiled form.
+func (l *Lexicon) compile(d Distribution) *compiledLexicon {
+	l.rank(d)
+	compiled := &compiledLexicon{Name: l.Name, Definitions: l.defs}
+	for _, n := range l.lengths() {
+		for _, alpha := range l.ByProbability(n) {
+			compiled.Alphagrams = append(compiled.Alphagrams,
+				compiledAlphagram{
+					Alphagram:   alpha,
+					Words:       l.anagrams[alpha],
+					Probability: l.probs[alpha],
+				})
+		}
+	}
+	return compiled
+}
+
+// WriteCompiled compiles the lexicon with the given tile distribution
+// and writes it out.
+func (l *Lexicon) WriteCompiled(w io.Writer, d Distribution) error {
+	return json.NewEncoder(w).Encode(l.compile(d))
+}
+
+// ReadCompiled reads a compiled lexicon.
+func ReadCompiled(r io.Reader) (*Lexicon, error) {
+	compiled := &compiledLexicon{}
+	err := json.NewDecoder(r).Decode(compiled)
+	if err != nil {
+		return nil, err
+	}
+	lex := newLexicon(compiled.Name)
+	for _, ca := range compiled.Alphagrams {
+		lex.anagrams[ca.Alphagram] = ca.Words
+		lex.probs[ca.Alphagram] = ca.Probability
+	}
+	for word, def := range compiled.Definitions {
+		lex.defs[word] = def
+	}
+	lex.indexLetters()
+	return lex, nil
+}
+
+// LoadCompiled reads a compiled lexicon from a file.
+func LoadCompiled(path string) (*Lexicon, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadCompiled(f)
+}
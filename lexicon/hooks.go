@@ -0,0 +1,52 @@
+package lexicon
+
+// This file works out the hooks of a word: the letters that can go in
+// front of or behind it to make another word.
+
+import (
+	"sort"
+)
+
+// indexLetters keeps every letter used in the lexicon, so hooks can be
+// looked up without knowing the alphabet ahead of time.
+func (l *Lexicon) indexLetters() {
+	seen := make(map[rune]bool)
+	l.letters = []rune{}
+	for alpha := range l.anagrams {
+		for _, r := range alpha {
+			if !seen[r] {
+				seen[r] = true
+				l.letters = append(l.letters, r)
+			}
+		}
+	}
+	sort.Sort(runes(l.letters))
+}
+
+// Hooks returns the letters that make another word when put in front
+// of the word, and the ones that do when put behind it, in
+// alphabetical order.
+func (l *Lexicon) Hooks(word string) (string, string) {
+	word = Normalize(word)
+	var front, back []rune
+	for _, r := range l.letters {
+		if l.Contains(string(r) + word) {
+			front = append(front, r)
+		}
+		if l.Contains(word + string(r)) {
+			back = append(back, r)
+		}
+	}
+	return string(front), string(back)
+}
+
+// InnerHooks says whether the word is still a word without its first
+// letter, and without its last letter.
+func (l *Lexicon) InnerHooks(word string) (bool, bool) {
+	letters := []rune(Normalize(word))
+	if len(letters) < 2 {
+		return false, false
+	}
+	return l.Contains(string(letters[1:])),
+		l.Contains(string(letters[:len(letters)-1]))
+}
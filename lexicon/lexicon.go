@@ -0,0 +1,242 @@
+// Package lexicon keeps word lists in memory, so that alphagrams and
+// their anagrams can be looked up without going to Webolith.
+package lexicon
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Lexicon is a word list, indexed by alphagram.
+type Lexicon struct {
+	Name string
+	// alphagram -> words with those letters, sorted
+	anagrams map[string][]string
+	// word -> definition; not every word has one.
+	defs map[string]string
+	// alphagram -> probability order within its length, with the
+	// English tile distribution unless the lexicon was compiled with
+	// another one.
+	probs map[string]int
+	// Every letter the words are made of, sorted.
+	letters []rune
+	// word -> lexicon symbols; most words have none.
+	symbols map[string]string
+}
+
+// Normalize puts a word in the form that lexica keep words in: trimmed,
+// upper-cased, and in Unicode NFC. That way an Ñ typed as an N and a
+// combining tilde is the same as the Ñ in a Spanish lexicon.
+func Normalize(word string) string {
+	return norm.NFC.String(strings.ToUpper(strings.TrimSpace(word)))
+}
+
+// Alphagram returns the letters of a word in alphabetical order.
+func Alphagram(word string) string {
+	letters := []rune(Normalize(word))
+	sort.Sort(runes(letters))
+	return string(letters)
+}
+
+type runes []rune
+
+func (r runes) Len() int           { return len(r) }
+func (r runes) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r runes) Less(i, j int) bool { return r[i] < r[j] }
+
+// New makes a lexicon out of a list of words. Words are normalized
+// and duplicates are ignored.
+func New(name string, words []string) *Lexicon {
+	lex := newLexicon(name)
+	for _, word := range words {
+		lex.add(Normalize(word))
+	}
+	lex.sortAnagrams()
+	lex.indexLetters()
+	lex.rank(EnglishDistribution)
+	return lex
+}
+
+func newLexicon(name string) *Lexicon {
+	return &Lexicon{
+		Name:     name,
+		anagrams: make(map[string][]string),
+		defs:     make(map[string]string),
+		probs:    make(map[string]int),
+		symbols:  make(map[string]string),
+	}
+}
+
+func (l *Lexicon) add(word string) {
+	alpha := Alphagram(word)
+	for _, w := range l.anagrams[alpha] {
+		if w == word {
+			return
+		}
+	}
+	l.anagrams[alpha] = append(l.anagrams[alpha], word)
+}
+
+func (l *Lexicon) sortAnagrams() {
+	for _, words := range l.anagrams {
+		sort.Strings(words)
+	}
+}
+
+// Read reads a lexicon with one word per line. Anything after the word
+// on a line is taken to be its definition. Blank lines are skipped.
+func Read(name string, r io.Reader) (*Lexicon, error) {
+	lex := newLexicon(name)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		word := line
+		def := ""
+		if i := strings.IndexFunc(line, unicode.IsSpace); i != -1 {
+			word = line[:i]
+			def = strings.TrimSpace(line[i:])
+		}
+		word = Normalize(word)
+		lex.add(word)
+		if def != "" {
+			lex.defs[word] = def
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	lex.sortAnagrams()
+	lex.indexLetters()
+	lex.rank(EnglishDistribution)
+	return lex, nil
+}
+
+// Load reads a lexicon from a file.
+func Load(name string, path string) (*Lexicon, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(name, f)
+}
+
+// Anagrams returns every word that can be made with all of these
+// letters, in alphabetical order.
+func (l *Lexicon) Anagrams(letters string) []string {
+	return l.anagrams[Alphagram(letters)]
+}
+
+// Contains is true if the word is in this lexicon.
+func (l *Lexicon) Contains(word string) bool {
+	word = Normalize(word)
+	for _, w := range l.anagrams[Alphagram(word)] {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
+
+// Definition returns the definition of a word, or "" if there is none.
+func (l *Lexicon) Definition(word string) string {
+	return l.defs[Normalize(word)]
+}
+
+// Alphagrams returns every alphagram of the given length, sorted.
+func (l *Lexicon) Alphagrams(length int) []string {
+	alphas := []string{}
+	for alpha := range l.anagrams {
+		if len([]rune(alpha)) == length {
+			alphas = append(alphas, alpha)
+		}
+	}
+	sort.Strings(alphas)
+	return alphas
+}
+
+func (l *Lexicon) lengths() []int {
+	seen := make(map[int]bool)
+	lengths := []int{}
+	for alpha := range l.anagrams {
+		n := len([]rune(alpha))
+		if !seen[n] {
+			seen[n] = true
+			lengths = append(lengths, n)
+		}
+	}
+	sort.Ints(lengths)
+	return lengths
+}
+
+// NumWords is how many words are in this lexicon.
+func (l *Lexicon) NumWords() int {
+	n := 0
+	for _, words := range l.anagrams {
+		n += len(words)
+	}
+	return n
+}
+
+var registry = struct {
+	sync.RWMutex
+	lexica map[string]*Lexicon
+}{lexica: make(map[string]*Lexicon)}
+
+// Register makes a lexicon available by its name, replacing any that
+// was there before.
+func Register(l *Lexicon) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.lexica[l.Name] = l
+}
+
+// Get returns the registered lexicon with this name.
+func Get(name string) (*Lexicon, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	l, ok := registry.lexica[name]
+	return l, ok
+}
+
+// LoadDir loads and registers every word list (.txt) and compiled
+// lexicon (.lex) file in a directory, naming each lexicon after its
+// file (e.g. America.txt is America).
+func LoadDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".txt")
+		lex, err := Load(name, path)
+		if err != nil {
+			return fmt.Errorf("loading %v: %v", path, err)
+		}
+		Register(lex)
+	}
+	paths, err = filepath.Glob(filepath.Join(dir, "*"+CompiledExt))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		lex, err := LoadCompiled(path)
+		if err != nil {
+			return fmt.Errorf("loading %v: %v", path, err)
+		}
+		Register(lex)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package lexicon
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func loadTiny(t *testing.T) *Lexicon {
+	lex, err := Load("Tiny", "testdata/tiny.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lex
+}
+
+func TestAlphagram(t *testing.T) {
+	if Alphagram("retains") != "AEINRST" {
+		t.Errorf("Unexpected alphagram %v", Alphagram("retains"))
+	}
+	if Alphagram("ÑANDU") != "ADNUÑ" {
+		t.Errorf("Unexpected alphagram %v", Alphagram("ÑANDU"))
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if Normalize("  retains\t") != "RETAINS" {
+		t.Errorf("Unexpected word %q", Normalize("  retains\t"))
+	}
+	// A decomposed ñ comes back as the single letter Ñ.
+	if Normalize("n\u0303andu") != "ÑANDU" {
+		t.Errorf("Unexpected word %q", Normalize("n\u0303andu"))
+	}
+}
+
+func TestAnagrams(t *testing.T) {
+	lex := loadTiny(t)
+	anagrams := lex.Anagrams("SATIRE N")
+	if len(anagrams) != 0 {
+		t.Errorf("Spaces are letters too, got %v", anagrams)
+	}
+	anagrams = lex.Anagrams("tsrenia")
+	expected := []string{"NASTIER", "RETAINS", "RETINAS", "STAINER",
+		"STEARIN"}
+	if len(anagrams) != len(expected) {
+		t.Fatalf("Unexpected anagrams %v", anagrams)
+	}
+	for i := range expected {
+		if anagrams[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], anagrams[i])
+		}
+	}
+	if !lex.Contains("retina") || lex.Contains("RETIAN") {
+		t.Errorf("Contains is wrong")
+	}
+	if lex.Definition("QI") != "vital force [n]" {
+		t.Errorf("Unexpected definition %v", lex.Definition("QI"))
+	}
+	twos := lex.Alphagrams(2)
+	if len(twos) != 2 || twos[0] != "AZ" || twos[1] != "IQ" {
+		t.Errorf("Unexpected alphagrams %v", twos)
+	}
+	if lex.NumWords() != 11 {
+		t.Errorf("Should have had 11 words, had %v", lex.NumWords())
+	}
+}
+
+func TestFullQuestionsJSON(t *testing.T) {
+	lex := loadTiny(t)
+	body, err := lex.FullQuestionsJSON([]string{"AGIMNORS", "AIQ"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// This should read just like a Webolith response.
+	var questions []struct {
+		Question string `json:"question"`
+		Answers  []struct {
+			Word string `json:"word"`
+			Def  string `json:"def"`
+		} `json:"answers"`
+	}
+	if err := json.Unmarshal(body, &questions); err != nil {
+		t.Fatal(err)
+	}
+	if len(questions) != 2 || questions[0].Question != "AGIMNORS" ||
+		len(questions[0].Answers) != 2 ||
+		questions[0].Answers[1].Word != "ROAMINGS" ||
+		questions[0].Answers[0].Def != "any form of animal or plant life [n]" ||
+		len(questions[1].Answers) != 0 {
+		t.Errorf("Unexpected full questions %s", body)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	lex := loadTiny(t)
+	if front, back := lex.Hooks("retain"); front != "" || back != "S" {
+		t.Errorf("Unexpected hooks %q %q", front, back)
+	}
+	if front, back := lex.InnerHooks("RETINAS"); front || !back {
+		t.Errorf("Unexpected inner hooks %v %v", front, back)
+	}
+	if front, back := lex.InnerHooks("QI"); front || back {
+		t.Errorf("Unexpected inner hooks %v %v", front, back)
+	}
+	// Compiled lexica know their letters too.
+	var buf bytes.Buffer
+	if err := lex.WriteCompiled(&buf, EnglishDistribution); err != nil {
+		t.Fatal(err)
+	}
+	compiled, err := ReadCompiled(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, back := compiled.Hooks("RETINA"); back != "S" {
+		t.Errorf("Unexpected back hooks %q", back)
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	lex := loadTiny(t)
+	previous := New("Previous", []string{"RETAIN", "RETINA", "QI"})
+	other := New("Other", []string{"RETAIN", "ZA"})
+	lex.MarkMissing(previous, SymbolNew)
+	lex.MarkMissing(other, SymbolOnly)
+	lex.MarkMissing(other, SymbolOnly)
+	if lex.Symbols("retina") != SymbolOnly || lex.Symbols("ZA") != SymbolNew ||
+		lex.Symbols("NASTIER") != "+#" || lex.Symbols("RETAIN") != "" {
+		t.Errorf("Unexpected symbols %q %q %q %q", lex.Symbols("RETINA"),
+			lex.Symbols("ZA"), lex.Symbols("NASTIER"), lex.Symbols("RETAIN"))
+	}
+	answer := lex.FullQuestions([]string{"AEINRT"})[0].Answers[1]
+	if answer.Word != "RETINA" || answer.BackHooks != "S" ||
+		answer.Symbols != SymbolOnly || answer.Definition == "" {
+		t.Errorf("Unexpected answer %v", answer)
+	}
+}
+
+func TestMarkPairs(t *testing.T) {
+	Register(New("Newer", []string{"QI", "ZA"}))
+	Register(New("Older", []string{"QI"}))
+	if err := MarkPairs("Newer:Older", SymbolNew); err != nil {
+		t.Fatal(err)
+	}
+	newer, _ := Get("Newer")
+	if newer.Symbols("ZA") != SymbolNew || newer.Symbols("QI") != "" {
+		t.Errorf("Unexpected symbols %q %q", newer.Symbols("ZA"),
+			newer.Symbols("QI"))
+	}
+	if err := MarkPairs("Newer", SymbolNew); err == nil {
+		t.Errorf("Should need two lexica")
+	}
+	if err := MarkPairs("Newer:NotLoaded", SymbolNew); err == nil {
+		t.Errorf("Should not mark with a lexicon that isn't loaded")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	if err := LoadDir("testdata"); err != nil {
+		t.Fatal(err)
+	}
+	lex, ok := Get("tiny")
+	if !ok || lex.NumWords() != 11 {
+		t.Errorf("Should have registered the tiny lexicon")
+	}
+}
+
+func TestProbability(t *testing.T) {
+	lex := New("ProbOrder", []string{"RETAINS", "JUKEBOX", "ZAX", "CAT",
+		"SEAT", "EAST"})
+	if top := lex.TopByProbability(7, 1); len(top) != 1 || top[0] != "AEINRST" {
+		t.Errorf("Unexpected top 7s %v", top)
+	}
+	if lex.Probability("stainer") != 1 || lex.Probability("JUKEBOX") != 2 {
+		t.Errorf("Unexpected probabilities %d, %d",
+			lex.Probability("STAINER"), lex.Probability("JUKEBOX"))
+	}
+	if threes := lex.ByProbability(3); len(threes) != 2 || threes[0] != "ACT" {
+		t.Errorf("Unexpected 3s %v", threes)
+	}
+	questions := lex.FullQuestions([]string{"AEST"})
+	if questions[0].Probability != 1 {
+		t.Errorf("Full questions should have probabilities, got %v",
+			questions[0])
+	}
+}
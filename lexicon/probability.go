@@ -0,0 +1,130 @@
+package lexicon
+
+// This file works out how likely an alphagram is to be drawn from a
+// bag of tiles. Like Zyzzyva, we count the number of ways to draw the
+// alphagram's letters from the bag, and ignore blanks.
+
+import (
+	"sort"
+)
+
+// Distribution is how many tiles of each letter are in the bag.
+type Distribution map[rune]int
+
+// EnglishDistribution is the standard English tile distribution,
+// without the blanks.
+var EnglishDistribution = Distribution{
+	'A': 9, 'B': 2, 'C': 2, 'D': 4, 'E': 12, 'F': 2, 'G': 3, 'H': 2,
+	'I': 9, 'J': 1, 'K': 1, 'L': 4, 'M': 2, 'N': 6, 'O': 8, 'P': 2,
+	'Q': 1, 'R': 6, 'S': 4, 'T': 6, 'U': 4, 'V': 2, 'W': 2, 'X': 1,
+	'Y': 2, 'Z': 1,
+}
+
+func choose(n int, k int) uint64 {
+	if k < 0 || k > n {
+		return 0
+	}
+	result := uint64(1)
+	for i := 1; i <= k; i++ {
+		result = result * uint64(n-k+i) / uint64(i)
+	}
+	return result
+}
+
+// Combinations is the number of ways the letters of the alphagram can
+// be drawn from a bag with this distribution. It is 0 if the bag
+// doesn't have enough of some letter.
+func (d Distribution) Combinations(alphagram string) uint64 {
+	counts := make(map[rune]int)
+	for _, letter := range alphagram {
+		counts[letter]++
+	}
+	combos := uint64(1)
+	for letter, count := range counts {
+		combos *= choose(d[letter], count)
+	}
+	return combos
+}
+
+// byProbability sorts alphagrams from most to least likely. Ties are
+// broken alphabetically, so the order is always the same.
+type byProbability struct {
+	alphagrams []string
+	combos     map[string]uint64
+}
+
+func (b byProbability) Len() int { return len(b.alphagrams) }
+func (b byProbability) Swap(i, j int) {
+	b.alphagrams[i], b.alphagrams[j] = b.alphagrams[j], b.alphagrams[i]
+}
+func (b byProbability) Less(i, j int) bool {
+	ci, cj := b.combos[b.alphagrams[i]], b.combos[b.alphagrams[j]]
+	if ci != cj {
+		return ci > cj
+	}
+	return b.alphagrams[i] < b.alphagrams[j]
+}
+
+// rankByProbability returns each alphagram's probability order,
+// starting from 1 for the most likely. The alphagrams should all be the
+// same length.
+func rankByProbability(alphagrams []string, d Distribution) map[string]int {
+	b := byProbability{
+		alphagrams: make([]string, len(alphagrams)),
+		combos:     make(map[string]uint64),
+	}
+	copy(b.alphagrams, alphagrams)
+	for _, alpha := range b.alphagrams {
+		b.combos[alpha] = d.Combinations(alpha)
+	}
+	sort.Sort(b)
+	ranks := make(map[string]int)
+	for i, alpha := range b.alphagrams {
+		ranks[alpha] = i + 1
+	}
+	return ranks
+}
+
+// rank works out the probability order of every alphagram in the
+// lexicon, within its length.
+func (l *Lexicon) rank(d Distribution) {
+	byLength := make(map[int][]string)
+	for alpha := range l.anagrams {
+		n := len([]rune(alpha))
+		byLength[n] = append(byLength[n], alpha)
+	}
+	l.probs = make(map[string]int)
+	for _, alphas := range byLength {
+		for alpha, rank := range rankByProbability(alphas, d) {
+			l.probs[alpha] = rank
+		}
+	}
+}
+
+// Probability returns the alphagram's probability order among the
+// alphagrams of its length, starting from 1 for the most likely. It is
+// 0 if the alphagram is not in the lexicon.
+func (l *Lexicon) Probability(alphagram string) int {
+	return l.probs[Alphagram(alphagram)]
+}
+
+// ByProbability returns every alphagram of the given length, from most
+// to least likely.
+func (l *Lexicon) ByProbability(length int) []string {
+	alphas := l.Alphagrams(length)
+	ordered := make([]string, len(alphas))
+	for _, alpha := range alphas {
+		ordered[l.probs[alpha]-1] = alpha
+	}
+	return ordered
+}
+
+// TopByProbability returns the n most likely alphagrams of the given
+// length, or all of them if there are fewer than n.
+func (l *Lexicon) TopByProbability(length int, n int) []string {
+	alphas := l.ByProbability(length)
+	if n < len(alphas) {
+		alphas = alphas[:n]
+	}
+	return alphas
+}
@@ -0,0 +1,60 @@
+package lexicon
+
+// This file turns alphagrams into the same full question JSON that
+// Webolith's /base/api/word_db/full_questions/ returns.
+
+import (
+	"encoding/json"
+)
+
+// FullAnswer is a single answer to a question, with its word info.
+type FullAnswer struct {
+	Word       string `json:"word"`
+	Definition string `json:"def"`
+	FrontHooks string `json:"f_hooks"`
+	BackHooks  string `json:"b_hooks"`
+	FrontInner bool   `json:"f_inner"`
+	BackInner  bool   `json:"b_inner"`
+	Symbols    string `json:"symbols"`
+}
+
+// FullQuestion is an alphagram with all of its answers.
+type FullQuestion struct {
+	Question    string       `json:"question"`
+	Answers     []FullAnswer `json:"answers"`
+	Probability int          `json:"probability"`
+}
+
+// FullQuestions looks up the answers to each alphagram, keeping the
+// order they were given in.
+func (l *Lexicon) FullQuestions(alphagrams []string) []FullQuestion {
+	questions := make([]FullQuestion, len(alphagrams))
+	for i, alpha := range alphagrams {
+		questions[i] = FullQuestion{
+			Question:    alpha,
+			Answers:     []FullAnswer{},
+			Probability: l.Probability(alpha),
+		}
+		for _, word := range l.Anagrams(alpha) {
+			questions[i].Answers = append(questions[i].Answers,
+				l.fullAnswer(word))
+		}
+	}
+	return questions
+}
+
+func (l *Lexicon) fullAnswer(word string) FullAnswer {
+	answer := FullAnswer{
+		Word:       word,
+		Definition: l.Definition(word),
+		Symbols:    l.Symbols(word),
+	}
+	answer.FrontHooks, answer.BackHooks = l.Hooks(word)
+	answer.FrontInner, answer.BackInner = l.InnerHooks(word)
+	return answer
+}
+
+// FullQuestionsJSON is FullQuestions, encoded the way Webolith does it.
+func (l *Lexicon) FullQuestionsJSON(alphagrams []string) ([]byte, error) {
+	return json.Marshal(l.FullQuestions(alphagrams))
+}
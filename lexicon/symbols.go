@@ -0,0 +1,61 @@
+package lexicon
+
+// This file marks the words that are new in a lexicon, or only in that
+// lexicon, with the symbols that get shown after them.
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The symbols Webolith puts after words, the same as Zyzzyva does.
+const (
+	// The word is new in this edition of the lexicon.
+	SymbolNew = "+"
+	// The word is only in this lexicon, e.g. a Collins-only word.
+	SymbolOnly = "#"
+)
+
+// MarkMissing gives every word that isn't in the other lexicon the
+// symbol. This should be done before the lexicon is in use, since
+// nothing else about a lexicon changes once it's loaded.
+func (l *Lexicon) MarkMissing(other *Lexicon, symbol string) {
+	for _, words := range l.anagrams {
+		for _, word := range words {
+			if !other.Contains(word) &&
+				!strings.Contains(l.symbols[word], symbol) {
+				l.symbols[word] += symbol
+			}
+		}
+	}
+}
+
+// Symbols returns the lexicon symbols for the word, or "" if it has
+// none.
+func (l *Lexicon) Symbols(word string) string {
+	return l.symbols[Normalize(word)]
+}
+
+// MarkPairs marks the registered lexica with the symbol, for a list of
+// lexicon:other pairs like "America:OWL3,CSW19:CSW15".
+func MarkPairs(pairs string, symbol string) error {
+	if pairs == "" {
+		return nil
+	}
+	for _, pair := range strings.Split(pairs, ",") {
+		names := strings.Split(pair, ":")
+		if len(names) != 2 {
+			return fmt.Errorf("%q should be lexicon:other", pair)
+		}
+		lex, ok := Get(names[0])
+		if !ok {
+			return fmt.Errorf("lexicon %v is not loaded", names[0])
+		}
+		other, ok := Get(names[1])
+		if !ok {
+			return fmt.Errorf("lexicon %v is not loaded", names[1])
+		}
+		lex.MarkMissing(other, symbol)
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package lexicon
+
+// This file handles custom lexicon uploads. An uploaded word list is
+// checked and compiled in the background, and then registered so that
+// word lists can use it.
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/domino14/gosports/channels"
+)
+
+// The most problems we report for a single upload.
+const maxProblems = 100
+
+// The biggest word list we accept, in bytes.
+const maxUploadSize = 20 << 20
+
+// How many finished jobs we keep around for their status to be looked
+// up. Older ones are forgotten.
+const maxFinishedJobs = 100
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// Problem is something wrong with a line of an uploaded word list.
+type Problem struct {
+	Line   int    `json:"line"`
+	Word   string `json:"word"`
+	Reason string `json:"reason"`
+}
+
+// Validate reads a plain word list, with one word per line, and checks
+// that every word is made of letters only and shows up just once.
+// Blank lines are skipped. Returns the upper-cased words, and any
+// problems found.
+func Validate(r io.Reader) ([]string, []Problem, error) {
+	words := []string{}
+	problems := []Problem{}
+	seen := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() && len(problems) < maxProblems {
+		lineNum++
+		word := Normalize(scanner.Text())
+		if word == "" {
+			continue
+		}
+		if i := strings.IndexFunc(word, isBadChar); i != -1 {
+			bad, _ := utf8.DecodeRuneInString(word[i:])
+			problems = append(problems, Problem{Line: lineNum, Word: word,
+				Reason: fmt.Sprintf("bad character %q", string(bad))})
+			continue
+		}
+		if first, ok := seen[word]; ok {
+			problems = append(problems, Problem{Line: lineNum, Word: word,
+				Reason: fmt.Sprintf("duplicate of line %d", first)})
+			continue
+		}
+		seen[word] = lineNum
+		words = append(words, word)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return words, problems, nil
+}
+
+func isBadChar(r rune) bool {
+	return !unicode.IsLetter(r)
+}
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobCompiling JobStatus = "compiling"
+	JobDone      JobStatus = "done"
+	JobFailed    JobStatus = "failed"
+)
+
+// Job is a lexicon that was uploaded to be compiled.
+type Job struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Status   JobStatus `json:"status"`
+	NumWords int       `json:"numWords"`
+	Problems []Problem `json:"problems,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type compileRequest struct {
+	jobID string
+	body  []byte
+}
+
+// Compiler compiles uploaded lexica, one at a time, in the background.
+// Compiled lexica are written to Dir, so they are loaded again the next
+// time the server starts; if Dir is empty, they are only registered.
+type Compiler struct {
+	sync.RWMutex
+	Dir          string
+	Distribution Distribution
+	jobs         map[string]*Job
+	// Names that are queued or being compiled.
+	pending map[string]bool
+	// IDs of finished jobs, oldest first.
+	finished []string
+	queue    chan compileRequest
+	nextID   int
+}
+
+func NewCompiler(dir string, d Distribution) *Compiler {
+	return &Compiler{
+		Dir:          dir,
+		Distribution: d,
+		jobs:         make(map[string]*Job),
+		pending:      make(map[string]bool),
+		queue:        make(chan compileRequest, 16),
+	}
+}
+
+// Submit queues a word list to be compiled under the given name. The
+// name can't be one that is already taken.
+func (c *Compiler) Submit(name string, body []byte) (Job, error) {
+	if !validName.MatchString(name) {
+		return Job{}, fmt.Errorf("bad lexicon name: %v", name)
+	}
+	if _, ok := Get(name); ok {
+		return Job{}, fmt.Errorf("lexicon %v already exists", name)
+	}
+	c.Lock()
+	defer c.Unlock()
+	if c.pending[name] {
+		return Job{}, fmt.Errorf("lexicon %v is already being compiled", name)
+	}
+	c.nextID++
+	job := &Job{ID: strconv.Itoa(c.nextID), Name: name, Status: JobQueued}
+	select {
+	case c.queue <- compileRequest{jobID: job.ID, body: body}:
+	default:
+		return Job{}, fmt.Errorf("too many lexica are being compiled")
+	}
+	c.jobs[job.ID] = job
+	c.pending[name] = true
+	return *job, nil
+}
+
+// Job returns the status of a job.
+func (c *Compiler) Job(id string) (Job, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	job, ok := c.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+func (c *Compiler) update(id string, update func(job *Job)) {
+	c.Lock()
+	defer c.Unlock()
+	job := c.jobs[id]
+	update(job)
+	if job.Status == JobDone || job.Status == JobFailed {
+		delete(c.pending, job.Name)
+		c.finished = append(c.finished, id)
+		for len(c.finished) > maxFinishedJobs {
+			delete(c.jobs, c.finished[0])
+			c.finished = c.finished[1:]
+		}
+	}
+}
+
+// Run compiles queued word lists. It does not return, so it should be
+// run in its own goroutine.
+func (c *Compiler) Run() {
+	for req := range c.queue {
+		c.compile(req)
+	}
+}
+
+func (c *Compiler) compile(req compileRequest) {
+	job, _ := c.Job(req.jobID)
+	log.Printf("[DEBUG] Compiling lexicon %s (job %s)", job.Name, job.ID)
+	c.update(job.ID, func(j *Job) { j.Status = JobCompiling })
+	fail := func(err error, problems []Problem) {
+		log.Printf("[ERROR] Compiling lexicon %s: %v", job.Name, err)
+		c.update(job.ID, func(j *Job) {
+			j.Status = JobFailed
+			j.Error = err.Error()
+			j.Problems = problems
+		})
+	}
+	words, problems, err := Validate(bytes.NewReader(req.body))
+	if err != nil {
+		fail(err, nil)
+		return
+	}
+	if len(problems) > 0 {
+		fail(fmt.Errorf("the word list has problems"), problems)
+		return
+	}
+	if len(words) == 0 {
+		fail(fmt.Errorf("the word list is empty"), nil)
+		return
+	}
+	lex := New(job.Name, words)
+	var compiled bytes.Buffer
+	err = lex.WriteCompiled(&compiled, c.Distribution)
+	if err != nil {
+		fail(err, nil)
+		return
+	}
+	if c.Dir != "" {
+		path := filepath.Join(c.Dir, job.Name+CompiledExt)
+		tmp := path + ".tmp"
+		err = ioutil.WriteFile(tmp, compiled.Bytes(), 0644)
+		if err == nil {
+			err = os.Rename(tmp, path)
+		}
+		if err != nil {
+			fail(err, nil)
+			return
+		}
+	}
+	Register(lex)
+	c.update(job.ID, func(j *Job) {
+		j.Status = JobDone
+		j.NumWords = len(words)
+	})
+	log.Printf("[DEBUG] Compiled lexicon %s with %d words", job.Name,
+		len(words))
+}
+
+// UploadHandler takes custom lexicon uploads from admins. Every request
+// has to be signed (see channels.VerifyUser).
+//
+//	POST ?name=<name>   with the word list as the body, queues it
+//	GET ?job=<id>       returns the status of a queued upload
+type UploadHandler struct {
+	Compiler *Compiler
+	// The users that may upload lexica.
+	Admins map[string]bool
+	Verify channels.Verifier
+}
+
+func (h UploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := channels.RequestUser(w, r, h.Verify)
+	if !ok {
+		return
+	}
+	if !h.Admins[user] {
+		http.Error(w, "Only admins can upload lexica", 403)
+		return
+	}
+	var job Job
+	status := 200
+	switch r.Method {
+	case "GET":
+		var ok bool
+		job, ok = h.Compiler.Job(r.URL.Query().Get("job"))
+		if !ok {
+			http.Error(w, "Not found", 404)
+			return
+		}
+	case "POST":
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxUploadSize+1))
+		if err != nil {
+			http.Error(w, "Could not read upload", 400)
+			return
+		}
+		if len(body) > maxUploadSize {
+			http.Error(w, "Upload too large", 413)
+			return
+		}
+		job, err = h.Compiler.Submit(r.URL.Query().Get("name"), body)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		status = 202
+	default:
+		http.Error(w, "Method not allowed", 405)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(job)
+}
@@ -0,0 +1,147 @@
+package lexicon
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	words, problems, err := Validate(strings.NewReader(
+		"retains\n\nSTAINER\nre-tain\nRetains\nNASTIER\nre—tain\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(words) != 3 || words[0] != "RETAINS" || words[2] != "NASTIER" {
+		t.Errorf("Unexpected words %v", words)
+	}
+	if len(problems) != 3 {
+		t.Fatalf("Expected 3 problems, got %v", problems)
+	}
+	if problems[0].Line != 4 || problems[0].Reason != `bad character "-"` {
+		t.Errorf("Unexpected problem %v", problems[0])
+	}
+	if problems[1].Line != 5 || problems[1].Reason != "duplicate of line 1" {
+		t.Errorf("Unexpected problem %v", problems[1])
+	}
+	// The whole character is reported, not just its first byte.
+	if problems[2].Line != 7 || problems[2].Reason != `bad character "—"` {
+		t.Errorf("Unexpected problem %v", problems[2])
+	}
+}
+
+func TestCompiledRoundTrip(t *testing.T) {
+	lex := loadTiny(t)
+	var buf bytes.Buffer
+	if err := lex.WriteCompiled(&buf, EnglishDistribution); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadCompiled(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Name != lex.Name || read.NumWords() != lex.NumWords() {
+		t.Errorf("Expected %v with %d words, got %v with %d", lex.Name,
+			lex.NumWords(), read.Name, read.NumWords())
+	}
+	if len(read.Anagrams("AEINRST")) != len(lex.Anagrams("AEINRST")) {
+		t.Errorf("Anagrams don't match: %v", read.Anagrams("AEINRST"))
+	}
+}
+
+func TestRankByProbability(t *testing.T) {
+	// There are many more ways to draw AEINRST than to draw JQXZ...
+	ranks := rankByProbability([]string{"CJKQXZZ", "AEINRST"},
+		EnglishDistribution)
+	if ranks["AEINRST"] != 1 || ranks["CJKQXZZ"] != 2 {
+		t.Errorf("Unexpected ranks %v", ranks)
+	}
+}
+
+func TestCompiler(t *testing.T) {
+	c := NewCompiler("", EnglishDistribution)
+	go c.Run()
+	if _, err := c.Submit("bad name", []byte("CAT\n")); err == nil {
+		t.Errorf("Should not take a bad name")
+	}
+	job, err := c.Submit("Uploaded", []byte("cat\nact\ndog\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad, err := c.Submit("Broken", []byte("cat\nc4t\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor := func(id string) Job {
+		for i := 0; i < 100; i++ {
+			j, _ := c.Job(id)
+			if j.Status == JobDone || j.Status == JobFailed {
+				return j
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("Job %v did not finish", id)
+		return Job{}
+	}
+	if j := waitFor(job.ID); j.Status != JobDone || j.NumWords != 3 {
+		t.Errorf("Unexpected job %v", j)
+	}
+	lex, ok := Get("Uploaded")
+	if !ok || len(lex.Anagrams("TAC")) != 2 {
+		t.Errorf("Should have registered the uploaded lexicon")
+	}
+	if j := waitFor(bad.ID); j.Status != JobFailed || len(j.Problems) != 1 {
+		t.Errorf("Unexpected job %v", j)
+	}
+	if _, err := c.Submit("Uploaded", []byte("CAT\n")); err == nil {
+		t.Errorf("Should not take a name that is already in use")
+	}
+}
+
+func TestCompilerForgetsOldJobs(t *testing.T) {
+	c := NewCompiler("", EnglishDistribution)
+	for i := 0; i < maxFinishedJobs+5; i++ {
+		id := strconv.Itoa(i)
+		c.jobs[id] = &Job{ID: id}
+		c.update(id, func(j *Job) { j.Status = JobFailed })
+	}
+	if len(c.jobs) != maxFinishedJobs {
+		t.Errorf("Expected %d jobs, got %d", maxFinishedJobs, len(c.jobs))
+	}
+	if _, ok := c.Job("0"); ok {
+		t.Errorf("The oldest job should have been forgotten")
+	}
+}
+
+func TestUploadHandler(t *testing.T) {
+	c := NewCompiler("", EnglishDistribution)
+	h := UploadHandler{Compiler: c, Admins: map[string]bool{"cesar": true},
+		Verify: func(v url.Values, now int64) (string, error) {
+			if v.Get("user") == "" {
+				return "", errors.New("no user was specified")
+			}
+			return v.Get("user"), nil
+		}}
+	do := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", path,
+			strings.NewReader("CAT\n")))
+		return rec
+	}
+	if rec := do("/lexicon/upload/?name=Mine"); rec.Code != 401 {
+		t.Errorf("Expected 401, got %d", rec.Code)
+	}
+	if rec := do("/lexicon/upload/?name=Mine&user=messi"); rec.Code != 403 {
+		t.Errorf("Expected 403, got %d", rec.Code)
+	}
+	rec := do("/lexicon/upload/?name=Mine&user=cesar")
+	if rec.Code != 202 ||
+		rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected response %d %v", rec.Code, rec.Header())
+	}
+}
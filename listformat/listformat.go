@@ -0,0 +1,244 @@
+// Package listformat reads and writes word lists in the text formats
+// that other study tools use, so users can move lists in and out of
+// Aerolith.
+//
+// The formats are:
+//
+//	alphagrams  one alphagram per line
+//	csv         alphagram,answers with the answers separated by spaces
+//	zyzzyva     a Zyzzyva anagram quiz file
+package listformat
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/domino14/gosports/lexicon"
+	"github.com/domino14/gosports/wordwalls"
+)
+
+type Format string
+
+const (
+	Alphagrams Format = "alphagrams"
+	CSV        Format = "csv"
+	Zyzzyva    Format = "zyzzyva"
+)
+
+// The most problems we report for a single import.
+const maxProblems = 100
+
+// ParseFormat turns a format name into a Format.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(name); f {
+	case Alphagrams, CSV, Zyzzyva:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown list format: %v", name)
+}
+
+// ContentType is the MIME type of an exported list.
+func (f Format) ContentType() string {
+	switch f {
+	case CSV:
+		return "text/csv; charset=utf-8"
+	case Zyzzyva:
+		return "application/xml; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// The parts of a Zyzzyva quiz file that we use.
+type zyzzyvaQuiz struct {
+	XMLName xml.Name `xml:"zyzzyva-quiz"`
+	Type    string   `xml:"type,attr"`
+	Lexicon string   `xml:"lexicon,attr"`
+	Method  string   `xml:"method,attr"`
+	Order   string   `xml:"question-order,attr"`
+	Source  zyzzyvaSource
+}
+
+type zyzzyvaSource struct {
+	XMLName   xml.Name `xml:"question-source"`
+	Type      string   `xml:"type,attr"`
+	Questions []string `xml:"questions>question"`
+}
+
+// questions returns the questions to export: all of them, or only the
+// ones that were missed.
+func questions(list *wordwalls.WordList, missedOnly bool) []wordwalls.Question {
+	if !missedOnly {
+		return list.OrigQuestions
+	}
+	missed := []wordwalls.Question{}
+	for _, idx := range list.Missed {
+		if idx >= 0 && idx < len(list.OrigQuestions) {
+			missed = append(missed, list.OrigQuestions[idx])
+		}
+	}
+	return missed
+}
+
+// Export writes out the list's questions in the given format. If
+// missedOnly is set, only the questions that were missed are written.
+func Export(w io.Writer, list *wordwalls.WordList, f Format,
+	missedOnly bool) error {
+	qs := questions(list, missedOnly)
+	switch f {
+	case Alphagrams:
+		bw := bufio.NewWriter(w)
+		for _, q := range qs {
+			fmt.Fprintln(bw, q.Question)
+		}
+		return bw.Flush()
+	case CSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"alphagram", "answers"})
+		for _, q := range qs {
+			cw.Write([]string{q.Question, strings.Join(q.Answers, " ")})
+		}
+		cw.Flush()
+		return cw.Error()
+	case Zyzzyva:
+		quiz := zyzzyvaQuiz{
+			Type:    "Anagrams",
+			Lexicon: list.Lexicon,
+			Method:  "Standard",
+			Order:   "Random",
+			Source:  zyzzyvaSource{Type: "list"},
+		}
+		for _, q := range qs {
+			quiz.Source.Questions = append(quiz.Source.Questions, q.Question)
+		}
+		io.WriteString(w, xml.Header)
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "  ")
+		if err := enc.Encode(quiz); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "\n")
+		return err
+	}
+	return fmt.Errorf("unknown list format: %v", f)
+}
+
+// A line of an imported list: the letters, and the answers if the
+// format has them.
+type importLine struct {
+	num     int
+	letters string
+	answers []string
+}
+
+func readLines(r io.Reader, f Format) ([]importLine, error) {
+	lines := []importLine{}
+	switch f {
+	case Alphagrams:
+		scanner := bufio.NewScanner(r)
+		num := 0
+		for scanner.Scan() {
+			num++
+			if letters := strings.TrimSpace(scanner.Text()); letters != "" {
+				lines = append(lines, importLine{num: num, letters: letters})
+			}
+		}
+		return lines, scanner.Err()
+	case CSV:
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		records, err := cr.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		for i, record := range records {
+			letters := strings.TrimSpace(record[0])
+			if letters == "" || (i == 0 && strings.EqualFold(letters, "alphagram")) {
+				continue
+			}
+			line := importLine{num: i + 1, letters: letters}
+			if len(record) > 1 {
+				line.answers = strings.Fields(record[1])
+			}
+			lines = append(lines, line)
+		}
+		return lines, nil
+	case Zyzzyva:
+		quiz := zyzzyvaQuiz{}
+		if err := xml.NewDecoder(r).Decode(&quiz); err != nil {
+			return nil, err
+		}
+		for i, q := range quiz.Source.Questions {
+			if letters := strings.TrimSpace(q); letters != "" {
+				// Zyzzyva has no line numbers, so count questions.
+				lines = append(lines, importLine{num: i + 1, letters: letters})
+			}
+		}
+		return lines, nil
+	}
+	return nil, fmt.Errorf("unknown list format: %v", f)
+}
+
+// Import reads a list in the given format, and makes a new word list out
+// of it, named name. Every question has to have answers in the lexicon,
+// and any answers given in the file have to be in the lexicon too. The
+// answers to each question always come from the lexicon. If there are
+// any problems, no list is returned.
+func Import(r io.Reader, f Format, lex *lexicon.Lexicon, name string) (
+	*wordwalls.WordList, []lexicon.Problem, error) {
+	lines, err := readLines(r, f)
+	if err != nil {
+		return nil, nil, err
+	}
+	problems := []lexicon.Problem{}
+	qs := []wordwalls.Question{}
+	seen := make(map[string]int)
+	for _, line := range lines {
+		if len(problems) >= maxProblems {
+			break
+		}
+		alpha := lexicon.Alphagram(line.letters)
+		problem := func(word string, reason string) {
+			problems = append(problems, lexicon.Problem{Line: line.num,
+				Word: word, Reason: reason})
+		}
+		answers := lex.Anagrams(alpha)
+		if len(answers) == 0 {
+			problem(line.letters, "no words in "+lex.Name)
+			continue
+		}
+		if first, ok := seen[alpha]; ok {
+			problem(line.letters, fmt.Sprintf("duplicate of line %d", first))
+			continue
+		}
+		bad := false
+		for _, word := range line.answers {
+			if lexicon.Alphagram(word) != alpha {
+				problem(word, "not an anagram of "+alpha)
+				bad = true
+			} else if !lex.Contains(word) {
+				problem(word, "not in "+lex.Name)
+				bad = true
+			}
+		}
+		if bad {
+			continue
+		}
+		seen[alpha] = line.num
+		qs = append(qs, wordwalls.Question{
+			Question:    alpha,
+			Answers:     answers,
+			Probability: lex.Probability(alpha),
+		})
+	}
+	if len(problems) > 0 {
+		return nil, problems, nil
+	}
+	if len(qs) == 0 {
+		return nil, nil, fmt.Errorf("the list has no questions")
+	}
+	return wordwalls.NewWordList(lex.Name, name, qs), nil, nil
+}
@@ -0,0 +1,89 @@
+package listformat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/domino14/gosports/lexicon"
+	"github.com/domino14/gosports/wordwalls"
+)
+
+var testLex = lexicon.New("Test", []string{"RETAINS", "STAINER", "TRAINED",
+	"QI", "ZA"})
+
+func testList() *wordwalls.WordList {
+	list := wordwalls.NewWordList("Test", "Test list", []wordwalls.Question{
+		{Question: "AEINRST", Answers: []string{"RETAINS", "STAINER"}},
+		{Question: "ADEINRT", Answers: []string{"TRAINED"}},
+		{Question: "IQ", Answers: []string{"QI"}},
+	})
+	list.Missed = []int{2}
+	list.NumMissed = 1
+	return list
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, f := range []Format{Alphagrams, CSV, Zyzzyva} {
+		var buf bytes.Buffer
+		if err := Export(&buf, testList(), f, false); err != nil {
+			t.Fatal(err)
+		}
+		list, problems, err := Import(&buf, f, testLex, "Imported")
+		if err != nil || len(problems) > 0 {
+			t.Fatalf("%v: could not import: %v %v", f, err, problems)
+		}
+		if list.NumQuestions != 3 || list.Name != "Imported" ||
+			list.OrigQuestions[0].Question != "AEINRST" ||
+			len(list.OrigQuestions[0].Answers) != 2 ||
+			list.OrigQuestions[2].Question != "IQ" {
+			t.Errorf("%v: unexpected list %v", f, list.OrigQuestions)
+		}
+	}
+}
+
+func TestExportMissed(t *testing.T) {
+	var buf bytes.Buffer
+	Export(&buf, testList(), CSV, true)
+	if buf.String() != "alphagram,answers\nIQ,QI\n" {
+		t.Errorf("Unexpected export %q", buf.String())
+	}
+	buf.Reset()
+	Export(&buf, testList(), Zyzzyva, true)
+	if !strings.Contains(buf.String(), `<zyzzyva-quiz type="Anagrams" `+
+		`lexicon="Test" method="Standard" question-order="Random">`) ||
+		!strings.Contains(buf.String(), "<question>IQ</question>") ||
+		strings.Contains(buf.String(), "AEINRST") {
+		t.Errorf("Unexpected export %s", buf.String())
+	}
+}
+
+func TestImportProblems(t *testing.T) {
+	_, problems, err := Import(strings.NewReader(
+		"alphagram,answers\n"+
+			"AEINRST,RETAINS STAINER\n"+
+			"XYZ,\n"+
+			"ADEINRT,TRAINED DETRAIN\n"+
+			"AEINRST,RETINAS\n"+
+			"SRETAIN,NASTIER\n"), CSV, testLex, "Bad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []lexicon.Problem{
+		{Line: 3, Word: "XYZ", Reason: "no words in Test"},
+		{Line: 4, Word: "DETRAIN", Reason: "not in Test"},
+		{Line: 5, Word: "AEINRST", Reason: "duplicate of line 2"},
+		{Line: 6, Word: "SRETAIN", Reason: "duplicate of line 2"},
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Unexpected problems %v", problems)
+	}
+	for i := range expected {
+		if problems[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], problems[i])
+		}
+	}
+	if _, err := ParseFormat("pdf"); err == nil {
+		t.Errorf("Should not know the pdf format")
+	}
+}
@@ -4,16 +4,54 @@ import (
 	"flag"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"text/template"
+	"time"
 
 	// "github.com/gorilla/rpc/v2"
 	// "github.com/gorilla/rpc/v2/json2"
 
+	"github.com/domino14/gosports/cardbox"
 	"github.com/domino14/gosports/channels"
+	"github.com/domino14/gosports/leaderboard"
+	"github.com/domino14/gosports/lexicon"
+	"github.com/domino14/gosports/savedlist"
+	"github.com/domino14/gosports/stats"
 	"github.com/domino14/gosports/wordwalls"
 )
 
 var addr = flag.String("addr", ":8080", "http service address")
+var multiTab = flag.String("multitab", "takeover",
+	"what to do when a user opens a table twice: takeover or allow")
+var lexiconDir = flag.String("lexicon-dir", "",
+	"directory of word list files (one word per line) to load as lexica")
+var lexiconPrevious = flag.String("lexicon-previous", "",
+	"comma-separated lexicon:previous edition pairs, to mark new words with +")
+var lexiconOthers = flag.String("lexicon-others", "",
+	"comma-separated lexicon:other lexicon pairs, to mark the words "+
+		"that are only in the first with #")
+var lexiconAdmins = flag.String("lexicon-admins", "",
+	"comma-separated users that may upload custom lexica")
+var challengeTZ = flag.String("challenge-tz", "America/Los_Angeles",
+	"time zone whose midnight starts a new day of challenges")
+var challengeLexica = flag.String("challenge-lexica", "America,CSW15",
+	"comma-separated lexica to make daily challenges for")
+var challengeLengths = flag.String("challenge-lengths",
+	"2,3,4,5,6,7,8,9,10,11,12,13,14,15",
+	"comma-separated word lengths to make daily challenges for")
+var leaderboardFile = flag.String("leaderboard-file", "leaderboards.json",
+	"file to keep daily challenge leaderboards in")
+var missedBingoFile = flag.String("missed-bingo-file", "missed_bingos.json",
+	"file to keep count of the bingos missed in daily challenges in")
+var savedListFile = flag.String("saved-list-file", "saved_lists.json",
+	"file to keep users' saved word lists in")
+var cardboxFile = flag.String("cardbox-file", "cardboxes.json",
+	"file to keep users' cardboxes in")
+var cardboxIntervals = flag.String("cardbox-intervals", "1,3,7,14,30,60,120",
+	"comma-separated days that a card waits in each cardbox box")
+var statsFile = flag.String("stats-file", "stats.json",
+	"file to keep users' round results in")
 var homeTempl = template.Must(template.ParseFiles("home.html"))
 
 func serveHome(w http.ResponseWriter, r *http.Request) {
@@ -31,19 +69,107 @@ func serveHome(w http.ResponseWriter, r *http.Request) {
 
 func main() {
 	flag.Parse()
+	tabPolicy, err := wordwalls.ParseTabPolicy(*multiTab)
+	if err != nil {
+		log.Fatal(err)
+	}
+	wordwalls.MultiTabPolicy = tabPolicy
+	if *lexiconDir != "" {
+		err = lexicon.LoadDir(*lexiconDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = lexicon.MarkPairs(*lexiconPrevious, lexicon.SymbolNew)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = lexicon.MarkPairs(*lexiconOthers, lexicon.SymbolOnly)
+		if err != nil {
+			log.Fatal(err)
+		}
+		wordwalls.ChallengeAlphagrams = wordwalls.LocalLexica{}
+	}
+	loc, err := time.LoadLocation(*challengeTZ)
+	if err != nil {
+		log.Fatal(err)
+	}
+	wordwalls.ChallengeLocation = loc
+	var lengths []int
+	for _, l := range strings.Split(*challengeLengths, ",") {
+		length, err := strconv.Atoi(l)
+		if err != nil {
+			log.Fatal(err)
+		}
+		lengths = append(lengths, length)
+	}
+	leaderboards, err := leaderboard.NewFileStore(*leaderboardFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	wordwalls.Leaderboards = leaderboards
+	missedBingos, err := leaderboard.NewMissedBingoFile(*missedBingoFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	wordwalls.MissedBingos = missedBingos
+	go wordwalls.ScheduleDailyChallenges(
+		strings.Split(*challengeLexica, ","), lengths)
+	savedLists, err := savedlist.NewFileStore(*savedListFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	listService := &savedlist.Service{Store: savedLists, Shares: savedLists}
+	wordwalls.SharedLists = listService
+	schedule := cardbox.Schedule{}
+	for _, d := range strings.Split(*cardboxIntervals, ",") {
+		days, err := strconv.Atoi(d)
+		if err != nil {
+			log.Fatal(err)
+		}
+		schedule.Intervals = append(schedule.Intervals,
+			time.Duration(days)*24*time.Hour)
+	}
+	cardboxes, err := cardbox.NewFileStore(*cardboxFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	wordwalls.Cardboxes = cardbox.Recorder{Store: cardboxes, Schedule: schedule}
+	userStats, err := stats.NewFileStore(*statsFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	wordwalls.Stats = stats.Recorder{Store: userStats}
+	compiler := lexicon.NewCompiler(*lexiconDir, lexicon.EnglishDistribution)
+	go compiler.Run()
 	go channels.Hub.Run(wordwalls.MessageHandler)
 	http.HandleFunc("/", serveHome)
 	http.HandleFunc("/static/", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, r.URL.Path[1:])
 	})
 	http.HandleFunc("/ws", channels.ServeWs)
+	http.Handle("/leaderboard/", leaderboard.Handler{Store: leaderboards})
+	http.Handle("/missed_bingos/",
+		leaderboard.MissedBingoHandler{Store: missedBingos})
+	http.Handle("/lists/", savedlist.Handler{Service: listService})
+	http.Handle("/cardbox/",
+		cardbox.Handler{Store: cardboxes, Schedule: schedule})
+	http.Handle("/stats/", stats.Handler{Store: userStats,
+		LastSession: wordwalls.LastSession})
+	admins := make(map[string]bool)
+	for _, admin := range strings.Split(*lexiconAdmins, ",") {
+		if admin != "" {
+			admins[admin] = true
+		}
+	}
+	http.Handle("/lexicon/upload/", lexicon.UploadHandler{Compiler: compiler,
+		Admins: admins})
 
 	// s := rpc.NewServer()
 	// s.RegisterCodec(json2.NewCodec(), "application/json")
 	// s.RegisterService(new(wordwalls.WordwallsService), "")
 	// http.Handle("/rpc", s)
 
-	err := http.ListenAndServe(*addr, nil)
+	err = http.ListenAndServe(*addr, nil)
 	if err != nil {
 		log.Fatal("ListenAndServe: ", err)
 	}
@@ -0,0 +1,128 @@
+// Package namedlist makes the standard named lists for a lexicon (the
+// ones Webolith keeps in wordwalls_namedlist) out of the lexica loaded
+// in memory, as word lists that are ready to play.
+package namedlist
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/domino14/gosports/lexicon"
+	"github.com/domino14/gosports/wordwalls"
+)
+
+// The word lengths that we make lists for.
+const (
+	MinLength = 2
+	MaxLength = 8
+)
+
+// RangeSize is how many alphagrams go in each probability range list.
+const RangeSize = 500
+
+// The lengths that get probability range and vowel-heavy lists. The
+// other lengths are short enough to study whole.
+var rangeLengths = []int{7, 8}
+
+const vowels = "AEIOU"
+
+// Generate makes every named list for the lexicon. If previous is not
+// nil, it is the lexicon this one replaces, and the words that are new
+// in lex get lists of their own.
+func Generate(lex *lexicon.Lexicon, previous *lexicon.Lexicon) []*wordwalls.WordList {
+	lists := []*wordwalls.WordList{}
+	add := func(name string, alphas []string) {
+		if len(alphas) > 0 {
+			lists = append(lists, makeList(lex, name, alphas))
+		}
+	}
+	for length := MinLength; length <= MaxLength; length++ {
+		alphas := lex.ByProbability(length)
+		add(fmt.Sprintf("%s %ds", lex.Name, length), alphas)
+		add(fmt.Sprintf("%s JQXZ %ds", lex.Name, length),
+			filter(alphas, hasJQXZ))
+		if previous != nil {
+			add(fmt.Sprintf("New in %s %ds", lex.Name, length),
+				newAlphagrams(lex, previous, alphas))
+		}
+	}
+	for _, length := range rangeLengths {
+		alphas := lex.ByProbability(length)
+		for start := 0; start < len(alphas); start += RangeSize {
+			end := start + RangeSize
+			if end > len(alphas) {
+				end = len(alphas)
+			}
+			add(fmt.Sprintf("%s %ds (%d-%d)", lex.Name, length, start+1, end),
+				alphas[start:end])
+		}
+		add(fmt.Sprintf("%s vowel-heavy %ds", lex.Name, length),
+			filter(alphas, vowelHeavy))
+	}
+	return lists
+}
+
+// FileName turns a list's name into one that can be loaded with the
+// study command, e.g. "America 7s (1-500)" becomes "america-7s-1-500".
+func FileName(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
+
+func makeList(lex *lexicon.Lexicon, name string,
+	alphas []string) *wordwalls.WordList {
+	questions := make([]wordwalls.Question, len(alphas))
+	for i, alpha := range alphas {
+		questions[i] = wordwalls.Question{
+			Question:    alpha,
+			Answers:     lex.Anagrams(alpha),
+			Probability: lex.Probability(alpha),
+		}
+	}
+	list := wordwalls.NewWordList(lex.Name, name, questions)
+	// Named lists are kept, unlike the lists made up for a single game.
+	list.Temporary = false
+	return list
+}
+
+func filter(alphas []string, keep func(string) bool) []string {
+	kept := []string{}
+	for _, alpha := range alphas {
+		if keep(alpha) {
+			kept = append(kept, alpha)
+		}
+	}
+	return kept
+}
+
+func hasJQXZ(alpha string) bool {
+	return strings.ContainsAny(alpha, "JQXZ")
+}
+
+// vowelHeavy is true if more than half of the letters are vowels.
+func vowelHeavy(alpha string) bool {
+	n := 0
+	for _, letter := range alpha {
+		if strings.ContainsRune(vowels, letter) {
+			n++
+		}
+	}
+	return n*2 > len([]rune(alpha))
+}
+
+// newAlphagrams returns the alphagrams that have at least one word that
+// was not in the previous lexicon.
+func newAlphagrams(lex *lexicon.Lexicon, previous *lexicon.Lexicon,
+	alphas []string) []string {
+	return filter(alphas, func(alpha string) bool {
+		for _, word := range lex.Anagrams(alpha) {
+			if !previous.Contains(word) {
+				return true
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,70 @@
+package namedlist
+
+import (
+	"testing"
+
+	"github.com/domino14/gosports/lexicon"
+	"github.com/domino14/gosports/wordwalls"
+)
+
+func findList(lists []*wordwalls.WordList, name string) *wordwalls.WordList {
+	for _, list := range lists {
+		if list.Name == name {
+			return list
+		}
+	}
+	return nil
+}
+
+func TestGenerate(t *testing.T) {
+	old := lexicon.New("Old", []string{"QI", "ZA", "RETAINS", "AUREOLE"})
+	lex := lexicon.New("New", []string{"QI", "ZA", "OK", "RETAINS",
+		"STAINER", "AUREOLE", "JUKEBOX"})
+	lists := Generate(lex, old)
+
+	twos := findList(lists, "New 2s")
+	if twos == nil || twos.NumQuestions != 3 || len(twos.CurQuestions) != 3 ||
+		twos.Temporary {
+		t.Fatalf("Unexpected 2s list %v", twos)
+	}
+	jqxz := findList(lists, "New JQXZ 2s")
+	if jqxz == nil || jqxz.NumQuestions != 2 {
+		t.Errorf("Unexpected JQXZ list %v", jqxz)
+	}
+	newTwos := findList(lists, "New in New 2s")
+	if newTwos == nil || newTwos.NumQuestions != 1 ||
+		newTwos.OrigQuestions[0].Question != "KO" {
+		t.Errorf("Unexpected new 2s %v", newTwos)
+	}
+	newSevens := findList(lists, "New in New 7s")
+	if newSevens == nil || newSevens.NumQuestions != 2 {
+		t.Errorf("Unexpected new 7s %v", newSevens)
+	}
+	vowelHeavy := findList(lists, "New vowel-heavy 7s")
+	if vowelHeavy == nil || vowelHeavy.NumQuestions != 1 ||
+		vowelHeavy.OrigQuestions[0].Question != "AEELORU" {
+		t.Errorf("Unexpected vowel-heavy 7s %v", vowelHeavy)
+	}
+	sevens := findList(lists, "New 7s (1-3)")
+	if sevens == nil || sevens.OrigQuestions[0].Probability != 1 {
+		t.Errorf("Unexpected 7s range %v", sevens)
+	}
+	if findList(lists, "New 8s") != nil {
+		t.Errorf("Should not make empty lists")
+	}
+}
+
+func TestFileName(t *testing.T) {
+	for name, expected := range map[string]string{
+		"America 7s (1-500)":  "america-7s-1-500",
+		"New in CSW19 2s":     "new-in-csw19-2s",
+		"FISE vowel-heavy 8s": "fise-vowel-heavy-8s",
+		"FISE Ñ 2s":           "fise-ñ-2s",
+		"America  JQXZ  3s":   "america-jqxz-3s",
+	} {
+		if FileName(name) != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, name,
+				FileName(name))
+		}
+	}
+}
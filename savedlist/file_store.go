@@ -0,0 +1,191 @@
+package savedlist
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/domino14/gosports/jsonfile"
+	"github.com/domino14/gosports/wordwalls"
+)
+
+// FileStore is a Store and ShareStore that saves every list and share
+// in one JSON file (see the jsonfile package). New lists get IDs after
+// the highest one in the file.
+type FileStore struct {
+	sync.RWMutex
+	path   string
+	lists  map[int]Saved
+	shares map[string]Share
+	nextID int
+}
+
+// The contents of the file.
+type storeFile struct {
+	Lists  []Saved `json:"lists"`
+	Shares []Share `json:"shares"`
+}
+
+// NewFileStore makes a store backed by the file at path, loading any
+// lists that are already in it.
+func NewFileStore(path string) (*FileStore, error) {
+	fs := &FileStore{
+		path:   path,
+		lists:  make(map[int]Saved),
+		shares: make(map[string]Share),
+		nextID: 1,
+	}
+	saved := storeFile{}
+	err := jsonfile.Load(path, &saved)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range saved.Lists {
+		fs.lists[s.List.ID] = s
+		if s.List.ID >= fs.nextID {
+			fs.nextID = s.List.ID + 1
+		}
+	}
+	for _, share := range saved.Shares {
+		fs.shares[share.Code] = share
+	}
+	return fs, nil
+}
+
+type byID []Saved
+
+func (s byID) Len() int           { return len(s) }
+func (s byID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byID) Less(i, j int) bool { return s[i].List.ID < s[j].List.ID }
+
+type byCode []Share
+
+func (s byCode) Len() int           { return len(s) }
+func (s byCode) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byCode) Less(i, j int) bool { return s[i].Code < s[j].Code }
+
+// save writes the store out to its file. The caller must hold the lock.
+func (fs *FileStore) save() error {
+	if fs.path == "" {
+		return nil
+	}
+	saved := storeFile{Lists: []Saved{}, Shares: []Share{}}
+	for _, s := range fs.lists {
+		saved.Lists = append(saved.Lists, s)
+	}
+	sort.Sort(byID(saved.Lists))
+	for _, share := range fs.shares {
+		saved.Shares = append(saved.Shares, share)
+	}
+	sort.Sort(byCode(saved.Shares))
+	return jsonfile.Save(fs.path, saved)
+}
+
+func (fs *FileStore) Get(id int) (Saved, error) {
+	fs.RLock()
+	defer fs.RUnlock()
+	s, ok := fs.lists[id]
+	if !ok {
+		return Saved{}, ErrNotFound
+	}
+	return Saved{Owner: s.Owner, List: copyList(s.List)}, nil
+}
+
+func (fs *FileStore) ByOwner(owner string) ([]Saved, error) {
+	fs.RLock()
+	defer fs.RUnlock()
+	owned := []Saved{}
+	for _, s := range fs.lists {
+		if s.Owner == owner {
+			owned = append(owned, Saved{Owner: s.Owner, List: copyList(s.List)})
+		}
+	}
+	sort.Sort(byID(owned))
+	return owned, nil
+}
+
+func (fs *FileStore) Create(owner string, list *wordwalls.WordList) (Saved,
+	error) {
+	fs.Lock()
+	defer fs.Unlock()
+	list = copyList(list)
+	list.ID = fs.nextID
+	list.Version = 1
+	fs.nextID++
+	fs.lists[list.ID] = Saved{Owner: owner, List: list}
+	return Saved{Owner: owner, List: copyList(list)}, fs.save()
+}
+
+func (fs *FileStore) Update(list *wordwalls.WordList) (Saved, error) {
+	fs.Lock()
+	defer fs.Unlock()
+	s, ok := fs.lists[list.ID]
+	if !ok {
+		return Saved{}, ErrNotFound
+	}
+	if s.List.Version != list.Version {
+		return Saved{}, ErrConflict
+	}
+	list = copyList(list)
+	list.Version++
+	fs.lists[list.ID] = Saved{Owner: s.Owner, List: list}
+	return Saved{Owner: s.Owner, List: copyList(list)}, fs.save()
+}
+
+func (fs *FileStore) Delete(id int, version int) error {
+	fs.Lock()
+	defer fs.Unlock()
+	s, ok := fs.lists[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if s.List.Version != version {
+		return ErrConflict
+	}
+	delete(fs.lists, id)
+	for code, share := range fs.shares {
+		if share.ListID == id {
+			delete(fs.shares, code)
+		}
+	}
+	return fs.save()
+}
+
+func (fs *FileStore) AddShare(share Share) error {
+	fs.Lock()
+	defer fs.Unlock()
+	fs.shares[share.Code] = share
+	return fs.save()
+}
+
+func (fs *FileStore) Share(code string) (Share, error) {
+	fs.RLock()
+	defer fs.RUnlock()
+	share, ok := fs.shares[code]
+	if !ok {
+		return Share{}, ErrNotFound
+	}
+	return share, nil
+}
+
+func (fs *FileStore) SharesWith(user string) ([]Share, error) {
+	fs.RLock()
+	defer fs.RUnlock()
+	shares := []Share{}
+	for _, share := range fs.shares {
+		if share.With == user {
+			shares = append(shares, share)
+		}
+	}
+	sort.Sort(byCode(shares))
+	return shares, nil
+}
+
+func (fs *FileStore) DeleteShare(code string) error {
+	fs.Lock()
+	defer fs.Unlock()
+	if _, ok := fs.shares[code]; !ok {
+		return ErrNotFound
+	}
+	delete(fs.shares, code)
+	return fs.save()
+}
@@ -0,0 +1,194 @@
+package savedlist
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/domino14/gosports/channels"
+	"github.com/domino14/gosports/wordwalls"
+)
+
+// Handler serves a user's saved lists as JSON. It is mounted at
+// /lists/, and every request has to be signed (see channels.VerifyUser).
+//
+//	GET    /lists/                the user's lists, without questions
+//	POST   /lists/                save a new list
+//	GET    /lists/<id>            one list
+//	DELETE /lists/<id>?version=<v>
+//	POST   /lists/<id>/rename     {"name": <name>, "version": <v>}
+//	POST   /lists/<id>/reset      {"version": <v>}
+//	POST   /lists/<id>/share      {"with": <user>, "mode": "readonly"|"fork"}
+//	GET    /lists/shared/         the shares made with the user
+//	GET    /lists/shared/<code>   a shared list
+//	POST   /lists/shared/<code>/fork
+//	DELETE /lists/shared/<code>   stop sharing
+//	GET    /lists/<id>/export?format=<f>&missed=true
+//	POST   /lists/import?format=<f>&lexicon=<lex>&name=<name>
+//
+// See the listformat package for the export and import formats.
+// If the version doesn't match the saved list (e.g. it was changed in
+// another tab), the response is a 409.
+type Handler struct {
+	Service *Service
+	Verify  channels.Verifier
+}
+
+// The body of rename and reset requests.
+type changeRequest struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+}
+
+// The body of share requests.
+type shareRequest struct {
+	With string    `json:"with"`
+	Mode ShareMode `json:"mode"`
+}
+
+var (
+	errMethod     = errors.New("method not allowed")
+	errBadRequest = errors.New("bad request")
+)
+
+func errorStatus(err error) int {
+	switch err {
+	case errMethod:
+		return 405
+	case ErrBadList, ErrBadIndex, errBadRequest:
+		return 400
+	case ErrBadShare:
+		return 400
+	case ErrNotFound:
+		return 404
+	case ErrForbidden, ErrReadOnly:
+		return 403
+	case ErrConflict, ErrNameTaken:
+		return 409
+	}
+	return 500
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := errorStatus(err)
+	if status == 500 {
+		log.Println("[ERROR] Saved list request", err)
+		http.Error(w, "Internal error", 500)
+		return
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := channels.RequestUser(w, r, h.Verify)
+	if !ok {
+		return
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/lists"), "/")
+	parts := strings.Split(path, "/")
+	var resp interface{}
+	var err error
+	if path == "" {
+		resp, err = h.serveLists(user, r)
+	} else if parts[0] == "shared" {
+		resp, err = h.serveShared(user, parts[1:], r)
+	} else if path == "import" {
+		h.serveImport(w, user, r)
+		return
+	} else {
+		id, convErr := strconv.Atoi(parts[0])
+		if convErr != nil || len(parts) > 2 {
+			http.Error(w, "Not found", 404)
+			return
+		}
+		action := ""
+		if len(parts) == 2 {
+			action = parts[1]
+		}
+		if action == "export" {
+			h.serveExport(w, user, id, r)
+			return
+		}
+		resp, err = h.serveList(user, id, action, r)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h Handler) serveLists(user string, r *http.Request) (interface{},
+	error) {
+	switch r.Method {
+	case "GET":
+		return h.Service.Lists(user)
+	case "POST":
+		list := &wordwalls.WordList{}
+		if err := json.NewDecoder(r.Body).Decode(list); err != nil {
+			return nil, errBadRequest
+		}
+		return h.Service.Create(user, list)
+	}
+	return nil, errMethod
+}
+
+func (h Handler) serveList(user string, id int, action string,
+	r *http.Request) (interface{}, error) {
+	switch {
+	case action == "" && r.Method == "GET":
+		return h.Service.Get(user, id)
+	case action == "" && r.Method == "DELETE":
+		version, err := strconv.Atoi(r.URL.Query().Get("version"))
+		if err != nil {
+			return nil, errBadRequest
+		}
+		return struct{}{}, h.Service.Delete(user, id, version)
+	case action == "share":
+		if r.Method != "POST" {
+			return nil, errMethod
+		}
+		req := shareRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, errBadRequest
+		}
+		return h.Service.Share(user, id, req.With, req.Mode)
+	case action == "rename" || action == "reset":
+		if r.Method != "POST" {
+			return nil, errMethod
+		}
+		req := changeRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, errBadRequest
+		}
+		if action == "rename" {
+			return h.Service.Rename(user, id, req.Version, req.Name)
+		}
+		return h.Service.Reset(user, id, req.Version)
+	case action == "":
+		return nil, errMethod
+	}
+	return nil, ErrNotFound
+}
+
+func (h Handler) serveShared(user string, parts []string,
+	r *http.Request) (interface{}, error) {
+	switch {
+	case len(parts) > 2 || (len(parts) == 2 && parts[1] != "fork"):
+		return nil, ErrNotFound
+	case len(parts) == 0 && r.Method == "GET":
+		return h.Service.SharedWith(user)
+	case len(parts) == 1 && r.Method == "GET":
+		list, _, err := h.Service.Open(user, parts[0])
+		return list, err
+	case len(parts) == 1 && r.Method == "DELETE":
+		return struct{}{}, h.Service.Unshare(user, parts[0])
+	case len(parts) == 2 && r.Method == "POST":
+		return h.Service.Fork(user, parts[0])
+	}
+	return nil, errMethod
+}
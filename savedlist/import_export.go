@@ -0,0 +1,81 @@
+package savedlist
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/domino14/gosports/lexicon"
+	"github.com/domino14/gosports/listformat"
+)
+
+// The biggest list file we accept, in bytes.
+const maxImportSize = 5 << 20
+
+// importProblems is sent back when an imported list doesn't check out
+// against its lexicon.
+type importProblems struct {
+	Problems []lexicon.Problem `json:"problems"`
+}
+
+func (h Handler) serveExport(w http.ResponseWriter, user string, id int,
+	r *http.Request) {
+	if r.Method != "GET" {
+		writeError(w, errMethod)
+		return
+	}
+	q := r.URL.Query()
+	format, err := listformat.ParseFormat(q.Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	list, err := h.Service.Get(user, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	err = listformat.Export(w, list, format, q.Get("missed") == "true")
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+func (h Handler) serveImport(w http.ResponseWriter, user string,
+	r *http.Request) {
+	if r.Method != "POST" {
+		writeError(w, errMethod)
+		return
+	}
+	q := r.URL.Query()
+	format, err := listformat.ParseFormat(q.Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	lex, ok := lexicon.Get(q.Get("lexicon"))
+	if !ok {
+		http.Error(w, "Unknown lexicon", 400)
+		return
+	}
+	list, problems, err := listformat.Import(
+		io.LimitReader(r.Body, maxImportSize), format, lex, q.Get("name"))
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	if len(problems) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(400)
+		json.NewEncoder(w).Encode(importProblems{Problems: problems})
+		return
+	}
+	list, err = h.Service.Create(user, list)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
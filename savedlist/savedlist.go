@@ -0,0 +1,81 @@
+// Package savedlist keeps the word lists that users have saved, i.e.
+// what Webolith keeps in wordwalls_savedlist.
+package savedlist
+
+import (
+	"errors"
+
+	"github.com/domino14/gosports/wordwalls"
+)
+
+var (
+	ErrNotFound = errors.New("list not found")
+	// ErrConflict means the list was changed since it was read, e.g.
+	// from another tab.
+	ErrConflict  = errors.New("list was changed since it was loaded")
+	ErrForbidden = errors.New("list belongs to someone else")
+	ErrNameTaken = errors.New("there is already a list with that name")
+	ErrBadList   = errors.New("list has no name or no questions")
+	// ErrBadIndex means the list points at questions it doesn't have.
+	ErrBadIndex = wordwalls.ErrBadIndex
+)
+
+// Saved is a saved word list, and the user it belongs to.
+type Saved struct {
+	Owner string              `json:"owner"`
+	List  *wordwalls.WordList `json:"list"`
+}
+
+// Store keeps saved lists. Stores hand out copies, so changing a list
+// that came from a store doesn't change the store.
+type Store interface {
+	Get(id int) (Saved, error)
+	ByOwner(owner string) ([]Saved, error)
+	// Create saves a new list, giving it an ID and version 1.
+	Create(owner string, list *wordwalls.WordList) (Saved, error)
+	// Update replaces the list with the same ID. The list's Version has
+	// to be the stored version, or ErrConflict is returned; the stored
+	// version then goes up by one.
+	Update(list *wordwalls.WordList) (Saved, error)
+	// Delete deletes a list, if it is still at the given version.
+	Delete(id int, version int) error
+}
+
+// Summary describes a saved list, without its questions.
+type Summary struct {
+	ID               int    `json:"id"`
+	Name             string `json:"name"`
+	Lexicon          string `json:"lexicon"`
+	NumAlphagrams    int    `json:"numAlphagrams"`
+	NumCurAlphagrams int    `json:"numCurAlphagrams"`
+	NumMissed        int    `json:"numMissed"`
+	GoneThruOnce     bool   `json:"goneThruOnce"`
+	Version          int    `json:"version"`
+}
+
+func summarize(list *wordwalls.WordList) Summary {
+	return Summary{
+		ID:               list.ID,
+		Name:             list.Name,
+		Lexicon:          list.Lexicon,
+		NumAlphagrams:    list.NumQuestions,
+		NumCurAlphagrams: list.NumCurAlphagrams,
+		NumMissed:        list.NumMissed,
+		GoneThruOnce:     list.GoneThruOnce,
+		Version:          list.Version,
+	}
+}
+
+// copyList makes a deep copy of a word list.
+func copyList(list *wordwalls.WordList) *wordwalls.WordList {
+	c := *list
+	c.OrigQuestions = make([]wordwalls.Question, len(list.OrigQuestions))
+	for i, q := range list.OrigQuestions {
+		c.OrigQuestions[i] = q
+		c.OrigQuestions[i].Answers = append([]string{}, q.Answers...)
+	}
+	c.CurQuestions = append([]int{}, list.CurQuestions...)
+	c.Missed = append([]int{}, list.Missed...)
+	c.FirstMissed = append([]int{}, list.FirstMissed...)
+	return &c
+}
@@ -0,0 +1,238 @@
+package savedlist
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/domino14/gosports/lexicon"
+	"github.com/domino14/gosports/wordwalls"
+)
+
+func testList(name string) *wordwalls.WordList {
+	return &wordwalls.WordList{
+		Lexicon: "America",
+		Name:    name,
+		OrigQuestions: []wordwalls.Question{
+			{Question: "AEINRST", Answers: []string{"RETAINS", "STAINER"}},
+			{Question: "ADEINRT", Answers: []string{"TRAINED"}},
+		},
+	}
+}
+
+func TestServiceLists(t *testing.T) {
+	store, _ := NewFileStore("")
+	s := &Service{Store: store}
+	list, err := s.Create("cesar", testList("Sevens"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.ID == 0 || list.Version != 1 || list.NumCurAlphagrams != 2 {
+		t.Errorf("Unexpected new list %v", list)
+	}
+	if _, err := s.Create("cesar", testList("Sevens")); err != ErrNameTaken {
+		t.Errorf("Expected the name to be taken, got %v", err)
+	}
+	if _, err := s.Create("messi", testList("Sevens")); err != nil {
+		t.Errorf("Other users can use the same name, got %v", err)
+	}
+	if _, err := s.Get("messi", list.ID); err != ErrForbidden {
+		t.Errorf("Expected a forbidden error, got %v", err)
+	}
+
+	renamed, err := s.Rename("cesar", list.ID, 1, "My sevens")
+	if err != nil || renamed.Name != "My sevens" || renamed.Version != 2 {
+		t.Errorf("Unexpected rename %v, %v", renamed, err)
+	}
+	// Another tab still has version 1.
+	if _, err := s.Reset("cesar", list.ID, 1); err != ErrConflict {
+		t.Errorf("Expected a conflict, got %v", err)
+	}
+	summaries, _ := s.Lists("cesar")
+	if len(summaries) != 1 || summaries[0].Name != "My sevens" ||
+		summaries[0].NumAlphagrams != 2 {
+		t.Errorf("Unexpected summaries %v", summaries)
+	}
+	if err := s.Delete("cesar", list.ID, 1); err != ErrConflict {
+		t.Errorf("Expected a conflict, got %v", err)
+	}
+	if err := s.Delete("cesar", list.ID, 2); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.Get("cesar", list.ID); err != ErrNotFound {
+		t.Errorf("Expected the list to be gone, got %v", err)
+	}
+}
+
+func TestReset(t *testing.T) {
+	store, _ := NewFileStore("")
+	s := &Service{Store: store}
+	list := testList("Sevens")
+	list.CurQuestions = []int{1}
+	list.Missed = []int{1}
+	list.NumMissed = 1
+	list.GoneThruOnce = true
+	list, _ = s.Create("cesar", list)
+	if len(list.CurQuestions) != 1 {
+		t.Errorf("Should have kept the list's progress, got %v", list)
+	}
+	list, err := s.Reset("cesar", list.ID, list.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.CurQuestions) != 2 || list.NumMissed != 0 ||
+		list.GoneThruOnce {
+		t.Errorf("Unexpected reset list %v", list)
+	}
+}
+
+func TestCreateChecksIndices(t *testing.T) {
+	store, _ := NewFileStore("")
+	s := &Service{Store: store}
+	for i, bad := range []func(*wordwalls.WordList){
+		func(l *wordwalls.WordList) { l.QuestionIndex = 3 },
+		func(l *wordwalls.WordList) { l.QuestionIndex = -1 },
+		func(l *wordwalls.WordList) { l.CurQuestions = []int{0, 2} },
+		func(l *wordwalls.WordList) { l.Missed = []int{-1} },
+		func(l *wordwalls.WordList) { l.FirstMissed = []int{5} },
+	} {
+		list := testList("Sevens")
+		list.CurQuestions = []int{0, 1}
+		bad(list)
+		if _, err := s.Create("cesar", list); err != ErrBadIndex {
+			t.Errorf("%d: expected a bad index, got %v", i, err)
+		}
+	}
+	list := testList("Sevens")
+	list.CurQuestions = []int{0, 1}
+	list.QuestionIndex = 2
+	list.Missed = []int{1}
+	list.NumMissed = 50
+	list, err := s.Create("cesar", list)
+	if err != nil || list.NumMissed != 1 || list.NumCurAlphagrams != 2 {
+		t.Errorf("Unexpected list %v, %v", list, err)
+	}
+}
+
+func TestFileStorePersists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "savedlist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "lists.json")
+	store, _ := NewFileStore(path)
+	saved, _ := store.Create("cesar", testList("Sevens"))
+
+	store, err = NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := store.Get(saved.List.ID)
+	if err != nil || loaded.Owner != "cesar" || loaded.List.Name != "Sevens" {
+		t.Errorf("Unexpected loaded list %v, %v", loaded, err)
+	}
+	another, _ := store.Create("cesar", testList("More"))
+	if another.List.ID == saved.List.ID {
+		t.Errorf("Should not reuse list IDs")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	store, _ := NewFileStore("")
+	h := Handler{
+		Service: &Service{Store: store},
+		Verify: func(v url.Values, now int64) (string, error) {
+			if v.Get("user") == "" {
+				return "", fmt.Errorf("no user was specified")
+			}
+			return v.Get("user"), nil
+		},
+	}
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	body, _ := json.Marshal(testList("Sevens"))
+	rec := do("POST", "/lists/?user=cesar", string(body))
+	created := &wordwalls.WordList{}
+	json.Unmarshal(rec.Body.Bytes(), created)
+	if rec.Code != 200 || created.ID == 0 {
+		t.Fatalf("Unexpected create response %d %s", rec.Code, rec.Body)
+	}
+	// The reason a request wasn't signed right stays in the server log.
+	if rec := do("GET", "/lists/", ""); rec.Code != 401 ||
+		strings.TrimSpace(rec.Body.String()) != "Unauthorized" {
+		t.Errorf("Expected 401, got %d %s", rec.Code, rec.Body)
+	}
+	path := fmt.Sprintf("/lists/%d", created.ID)
+	if rec := do("GET", path+"?user=messi", ""); rec.Code != 403 {
+		t.Errorf("Expected 403, got %d", rec.Code)
+	}
+	rec = do("POST", path+"/rename?user=cesar",
+		`{"name": "Renamed", "version": 1}`)
+	if rec.Code != 200 {
+		t.Errorf("Unexpected rename response %d %s", rec.Code, rec.Body)
+	}
+	rec = do("POST", path+"/reset?user=cesar", `{"version": 1}`)
+	if rec.Code != 409 {
+		t.Errorf("Expected 409, got %d", rec.Code)
+	}
+	rec = do("GET", "/lists/?user=cesar", "")
+	summaries := []Summary{}
+	json.Unmarshal(rec.Body.Bytes(), &summaries)
+	if len(summaries) != 1 || summaries[0].Name != "Renamed" ||
+		summaries[0].Version != 2 {
+		t.Errorf("Unexpected lists %s", rec.Body)
+	}
+	if rec := do("DELETE", path+"?user=cesar&version=2", ""); rec.Code != 200 {
+		t.Errorf("Unexpected delete response %d %s", rec.Code, rec.Body)
+	}
+	if rec := do("GET", path+"?user=cesar", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rec.Code)
+	}
+}
+
+func TestImportExport(t *testing.T) {
+	lexicon.Register(lexicon.New("SavedTest", []string{"RETAINS", "QI"}))
+	store, _ := NewFileStore("")
+	h := Handler{
+		Service: &Service{Store: store, Shares: store},
+		Verify: func(v url.Values, now int64) (string, error) {
+			return v.Get("user"), nil
+		},
+	}
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	rec := do("POST", "/lists/import?user=cesar&format=alphagrams"+
+		"&lexicon=SavedTest&name=Mine", "QI\nZZZ\n")
+	if rec.Code != 400 || !strings.Contains(rec.Body.String(), `"line":2`) {
+		t.Errorf("Expected the import to fail, got %d %s", rec.Code, rec.Body)
+	}
+	rec = do("POST", "/lists/import?user=cesar&format=alphagrams"+
+		"&lexicon=SavedTest&name=Mine", "QI\nSTAINER\n")
+	list := &wordwalls.WordList{}
+	json.Unmarshal(rec.Body.Bytes(), list)
+	if rec.Code != 200 || list.ID == 0 || list.NumQuestions != 2 {
+		t.Fatalf("Unexpected import response %d %s", rec.Code, rec.Body)
+	}
+	rec = do("GET", fmt.Sprintf("/lists/%d/export?user=cesar&format=csv",
+		list.ID), "")
+	if rec.Code != 200 ||
+		rec.Body.String() != "alphagram,answers\nIQ,QI\nAEINRST,RETAINS\n" {
+		t.Errorf("Unexpected export %d %q", rec.Code, rec.Body)
+	}
+}
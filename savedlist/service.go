@@ -0,0 +1,134 @@
+package savedlist
+
+// This file contains the saved list operations that users can do, and
+// checks that they are only done to the user's own lists.
+
+import (
+	"github.com/domino14/gosports/wordwalls"
+)
+
+// Service does what users ask to do with their saved lists.
+type Service struct {
+	Store  Store
+	Shares ShareStore
+}
+
+// owned gets a list, and makes sure it belongs to the user.
+func (s *Service) owned(user string, id int) (*wordwalls.WordList, error) {
+	saved, err := s.Store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if saved.Owner != user {
+		return nil, ErrForbidden
+	}
+	return saved.List, nil
+}
+
+// checkName makes sure the user doesn't already have another list with
+// this name.
+func (s *Service) checkName(user string, id int, name string) error {
+	if name == "" {
+		return ErrBadList
+	}
+	lists, err := s.Store.ByOwner(user)
+	if err != nil {
+		return err
+	}
+	for _, saved := range lists {
+		if saved.List.Name == name && saved.List.ID != id {
+			return ErrNameTaken
+		}
+	}
+	return nil
+}
+
+// Lists sums up all of the user's lists.
+func (s *Service) Lists(user string) ([]Summary, error) {
+	lists, err := s.Store.ByOwner(user)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]Summary, len(lists))
+	for i, saved := range lists {
+		summaries[i] = summarize(saved.List)
+	}
+	return summaries, nil
+}
+
+// Get returns one of the user's lists.
+func (s *Service) Get(user string, id int) (*wordwalls.WordList, error) {
+	return s.owned(user, id)
+}
+
+// Create saves a new list for the user. If the list doesn't say which
+// questions are left, it starts from the beginning.
+func (s *Service) Create(user string, list *wordwalls.WordList) (
+	*wordwalls.WordList, error) {
+	if len(list.OrigQuestions) == 0 {
+		return nil, ErrBadList
+	}
+	if err := s.checkName(user, 0, list.Name); err != nil {
+		return nil, err
+	}
+	list.Temporary = false
+	list.NumQuestions = len(list.OrigQuestions)
+	if len(list.CurQuestions) == 0 {
+		list.Reset()
+	}
+	if err := list.CheckIndices(); err != nil {
+		return nil, err
+	}
+	list.NumCurAlphagrams = len(list.CurQuestions)
+	list.NumMissed = len(list.Missed)
+	list.NumFirstMissed = len(list.FirstMissed)
+	saved, err := s.Store.Create(user, list)
+	if err != nil {
+		return nil, err
+	}
+	return saved.List, nil
+}
+
+// Rename renames one of the user's lists. version is the version of
+// the list that the user was looking at.
+func (s *Service) Rename(user string, id int, version int, name string) (
+	*wordwalls.WordList, error) {
+	list, err := s.owned(user, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkName(user, id, name); err != nil {
+		return nil, err
+	}
+	list.Name = name
+	list.Version = version
+	saved, err := s.Store.Update(list)
+	if err != nil {
+		return nil, err
+	}
+	return saved.List, nil
+}
+
+// Reset starts one of the user's lists over from the beginning.
+func (s *Service) Reset(user string, id int, version int) (
+	*wordwalls.WordList, error) {
+	list, err := s.owned(user, id)
+	if err != nil {
+		return nil, err
+	}
+	list.Reset()
+	list.Version = version
+	saved, err := s.Store.Update(list)
+	if err != nil {
+		return nil, err
+	}
+	return saved.List, nil
+}
+
+// Delete deletes one of the user's lists.
+func (s *Service) Delete(user string, id int, version int) error {
+	if _, err := s.owned(user, id); err != nil {
+		return err
+	}
+	return s.Store.Delete(id, version)
+}
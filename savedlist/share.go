@@ -0,0 +1,140 @@
+package savedlist
+
+// This file contains list sharing. A list can be shared with one user,
+// or with anyone who has its link. Read-only shares let the other user
+// open and play the list; fork shares also let them save a copy of it
+// as a new list of their own, starting from the beginning.
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/domino14/gosports/wordwalls"
+)
+
+var (
+	ErrReadOnly = errors.New("list was shared read-only")
+	ErrBadShare = errors.New("bad share mode or user")
+)
+
+type ShareMode string
+
+const (
+	ShareReadOnly ShareMode = "readonly"
+	ShareFork     ShareMode = "fork"
+)
+
+// Share gives someone else access to a saved list.
+type Share struct {
+	// Code is what goes in the link to the shared list.
+	Code   string `json:"code"`
+	ListID int    `json:"listId"`
+	Owner  string `json:"owner"`
+	// With is the user the list is shared with. If it is empty, anyone
+	// with the link can open the list.
+	With string    `json:"with,omitempty"`
+	Mode ShareMode `json:"mode"`
+}
+
+// ShareStore keeps list shares.
+type ShareStore interface {
+	AddShare(s Share) error
+	Share(code string) (Share, error)
+	// SharesWith returns the shares made with this user by name.
+	SharesWith(user string) ([]Share, error)
+	DeleteShare(code string) error
+}
+
+func newShareCode() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Share shares one of the user's lists. If with is empty, the list is
+// shared with anyone who has the link.
+func (s *Service) Share(user string, id int, with string, mode ShareMode) (
+	Share, error) {
+	if _, err := s.owned(user, id); err != nil {
+		return Share{}, err
+	}
+	if (mode != ShareReadOnly && mode != ShareFork) || with == user {
+		return Share{}, ErrBadShare
+	}
+	code, err := newShareCode()
+	if err != nil {
+		return Share{}, err
+	}
+	share := Share{Code: code, ListID: id, Owner: user, With: with,
+		Mode: mode}
+	return share, s.Shares.AddShare(share)
+}
+
+// Open returns a list that was shared with the user.
+func (s *Service) Open(user string, code string) (*wordwalls.WordList,
+	Share, error) {
+	share, err := s.Shares.Share(code)
+	if err != nil {
+		return nil, Share{}, err
+	}
+	if share.With != "" && share.With != user && share.Owner != user {
+		return nil, Share{}, ErrForbidden
+	}
+	saved, err := s.Store.Get(share.ListID)
+	if err != nil {
+		return nil, Share{}, err
+	}
+	return saved.List, share, nil
+}
+
+// Fork saves a copy of a shared list as a new list of the user's own,
+// with none of the owner's progress.
+func (s *Service) Fork(user string, code string) (*wordwalls.WordList,
+	error) {
+	shared, share, err := s.Open(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if share.Mode != ShareFork {
+		return nil, ErrReadOnly
+	}
+	name := shared.Name
+	for i := 2; s.checkName(user, 0, name) == ErrNameTaken; i++ {
+		name = fmt.Sprintf("%s (%d)", shared.Name, i)
+	}
+	fork := wordwalls.NewWordList(shared.Lexicon, name, shared.OrigQuestions)
+	return s.Create(user, fork)
+}
+
+// SharedWith returns the lists that were shared with the user by name.
+func (s *Service) SharedWith(user string) ([]Share, error) {
+	return s.Shares.SharesWith(user)
+}
+
+// Unshare stops sharing a list.
+func (s *Service) Unshare(user string, code string) error {
+	share, err := s.Shares.Share(code)
+	if err != nil {
+		return err
+	}
+	if share.Owner != user {
+		return ErrForbidden
+	}
+	return s.Shares.DeleteShare(code)
+}
+
+// LoadShared loads a shared list to be played at a table. Nothing that
+// happens at the table is saved back to the owner's list.
+func (s *Service) LoadShared(user string, code string) (*wordwalls.WordList,
+	error) {
+	list, _, err := s.Open(user, code)
+	if err != nil {
+		return nil, err
+	}
+	list.Temporary = true
+	return list, nil
+}
@@ -0,0 +1,113 @@
+package savedlist
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSharing(t *testing.T) {
+	store, _ := NewFileStore("")
+	s := &Service{Store: store, Shares: store}
+	list := testList("Sevens")
+	list.CurQuestions = []int{1}
+	list, _ = s.Create("cesar", list)
+
+	if _, err := s.Share("messi", list.ID, "", ShareFork); err != ErrForbidden {
+		t.Errorf("Only the owner can share, got %v", err)
+	}
+	if _, err := s.Share("cesar", list.ID, "", "edit"); err != ErrBadShare {
+		t.Errorf("Expected a bad share, got %v", err)
+	}
+	readOnly, err := s.Share("cesar", list.ID, "messi", ShareReadOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Open("xavi", readOnly.Code); err != ErrForbidden {
+		t.Errorf("Only messi can open it, got %v", err)
+	}
+	opened, _, err := s.Open("messi", readOnly.Code)
+	if err != nil || opened.Name != "Sevens" {
+		t.Errorf("Unexpected shared list %v, %v", opened, err)
+	}
+	if _, err := s.Fork("messi", readOnly.Code); err != ErrReadOnly {
+		t.Errorf("Should not fork a read-only share, got %v", err)
+	}
+	shared, _ := s.SharedWith("messi")
+	if len(shared) != 1 || shared[0].Code != readOnly.Code {
+		t.Errorf("Unexpected shares %v", shared)
+	}
+	loaded, err := s.LoadShared("messi", readOnly.Code)
+	if err != nil || !loaded.Temporary {
+		t.Errorf("Loaded lists should be temporary, got %v, %v", loaded, err)
+	}
+
+	link, _ := s.Share("cesar", list.ID, "", ShareFork)
+	s.Create("xavi", testList("Sevens"))
+	fork, err := s.Fork("xavi", link.Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fork.ID == list.ID || fork.Name != "Sevens (2)" ||
+		len(fork.CurQuestions) != 2 || len(fork.OrigQuestions) != 2 {
+		t.Errorf("Unexpected fork %v", fork)
+	}
+	if err := s.Unshare("xavi", link.Code); err != ErrForbidden {
+		t.Errorf("Only the owner can unshare, got %v", err)
+	}
+	if err := s.Unshare("cesar", link.Code); err != nil {
+		t.Error(err)
+	}
+	if _, _, err := s.Open("xavi", link.Code); err != ErrNotFound {
+		t.Errorf("Expected the share to be gone, got %v", err)
+	}
+	// Deleting a list stops sharing it.
+	s.Delete("cesar", list.ID, list.Version)
+	if _, err := store.Share(readOnly.Code); err != ErrNotFound {
+		t.Errorf("Expected the share to be gone, got %v", err)
+	}
+}
+
+func TestShareHandler(t *testing.T) {
+	store, _ := NewFileStore("")
+	h := Handler{
+		Service: &Service{Store: store, Shares: store},
+		Verify: func(v url.Values, now int64) (string, error) {
+			return v.Get("user"), nil
+		},
+	}
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	list, _ := h.Service.Create("cesar", testList("Sevens"))
+	rec := do("POST", fmt.Sprintf("/lists/%d/share?user=cesar", list.ID),
+		`{"with": "messi", "mode": "fork"}`)
+	share := Share{}
+	json.Unmarshal(rec.Body.Bytes(), &share)
+	if rec.Code != 200 || share.Code == "" {
+		t.Fatalf("Unexpected share response %d %s", rec.Code, rec.Body)
+	}
+	if rec := do("GET", "/lists/shared/?user=messi", ""); rec.Code != 200 ||
+		!strings.Contains(rec.Body.String(), share.Code) {
+		t.Errorf("Unexpected shares response %d %s", rec.Code, rec.Body)
+	}
+	path := "/lists/shared/" + share.Code
+	if rec := do("GET", path+"?user=xavi", ""); rec.Code != 403 {
+		t.Errorf("Expected 403, got %d", rec.Code)
+	}
+	if rec := do("POST", path+"/fork?user=messi", ""); rec.Code != 200 {
+		t.Errorf("Unexpected fork response %d %s", rec.Code, rec.Body)
+	}
+	if rec := do("POST", path+"/other?user=messi", ""); rec.Code != 404 {
+		t.Errorf("Expected 404, got %d", rec.Code)
+	}
+	if rec := do("DELETE", path+"?user=cesar", ""); rec.Code != 200 {
+		t.Errorf("Unexpected unshare response %d %s", rec.Code, rec.Body)
+	}
+}
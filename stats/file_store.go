@@ -0,0 +1,54 @@
+package stats
+
+import (
+	"sync"
+
+	"github.com/domino14/gosports/jsonfile"
+)
+
+// MaxRounds is how many rounds are kept for each user. Older ones are
+// dropped.
+const MaxRounds = 1000
+
+// FileStore is a Store that saves each user's last MaxRounds rounds in
+// a JSON file (see the jsonfile package).
+type FileStore struct {
+	sync.RWMutex
+	path   string
+	rounds map[string][]Round
+}
+
+// NewFileStore makes a store backed by the file at path, loading any
+// rounds that are already in it.
+func NewFileStore(path string) (*FileStore, error) {
+	fs := &FileStore{path: path, rounds: make(map[string][]Round)}
+	err := jsonfile.Load(path, &fs.rounds)
+	if err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// save writes the store out to its file. The caller must hold the lock.
+func (fs *FileStore) save() error {
+	return jsonfile.Save(fs.path, fs.rounds)
+}
+
+func (fs *FileStore) Record(rounds map[string]Round) error {
+	fs.Lock()
+	defer fs.Unlock()
+	for user, r := range rounds {
+		kept := append(fs.rounds[user], r)
+		if len(kept) > MaxRounds {
+			kept = kept[len(kept)-MaxRounds:]
+		}
+		fs.rounds[user] = kept
+	}
+	return fs.save()
+}
+
+func (fs *FileStore) Rounds(user string) ([]Round, error) {
+	fs.RLock()
+	defer fs.RUnlock()
+	return append([]Round{}, fs.rounds[user]...), nil
+}
@@ -0,0 +1,53 @@
+package stats
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/domino14/gosports/wordwalls"
+)
+
+// The most recent rounds shown on a profile, unless asked for.
+const defaultRecent = 10
+
+// Handler serves user profiles as JSON:
+//
+//	GET /stats/<user>?lexicon=<lex>&recent=<n>
+type Handler struct {
+	Store Store
+	// LastSession looks up the user's last session at a table, for
+	// the profile. It is left out if this is nil.
+	LastSession func(user string) *wordwalls.SessionSummary
+}
+
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", 405)
+		return
+	}
+	user := strings.Trim(strings.TrimPrefix(r.URL.Path, "/stats"), "/")
+	if user == "" || strings.Contains(user, "/") {
+		http.Error(w, "Not found", 404)
+		return
+	}
+	q := r.URL.Query()
+	recent, err := strconv.Atoi(q.Get("recent"))
+	if err != nil {
+		recent = defaultRecent
+	}
+	rounds, err := h.Store.Rounds(user)
+	if err != nil {
+		log.Println("[ERROR] Getting stats", err)
+		http.Error(w, "Internal error", 500)
+		return
+	}
+	p := MakeProfile(user, rounds, q.Get("lexicon"), recent)
+	if h.LastSession != nil {
+		p.LastSession = h.LastSession(user)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,109 @@
+// Package stats keeps each user's wordwalls round results, and sums
+// them up for their profile page.
+package stats
+
+import (
+	"log"
+	"time"
+
+	"github.com/domino14/gosports/wordwalls"
+)
+
+// Round is how a user did in a single round.
+type Round struct {
+	Played   time.Time          `json:"played"`
+	Lexicon  string             `json:"lexicon"`
+	GameType wordwalls.GameType `json:"gameType"`
+	wordwalls.PlayerResult
+}
+
+// Store keeps users' rounds.
+type Store interface {
+	// Record adds a round for each of the users in one go.
+	Record(rounds map[string]Round) error
+	// Rounds returns the user's rounds, oldest first.
+	Rounds(user string) ([]Round, error)
+}
+
+// LengthStats is how a user does on words of one length.
+type LengthStats struct {
+	Found    int     `json:"found"`
+	Total    int     `json:"total"`
+	Accuracy float64 `json:"accuracy"`
+}
+
+// Profile sums up a user's rounds.
+type Profile struct {
+	User            string               `json:"user"`
+	Rounds          int                  `json:"rounds"`
+	Solved          int                  `json:"solved"`
+	Missed          int                  `json:"missed"`
+	ByLength        map[int]*LengthStats `json:"byLength"`
+	SolvesPerMinute float64              `json:"solvesPerMinute"`
+	// The most recent rounds, newest first.
+	Recent []Round `json:"recent"`
+	// The last session the user finished at a table, if there is one.
+	LastSession *wordwalls.SessionSummary `json:"lastSession,omitempty"`
+}
+
+// MakeProfile sums up the rounds, which should be oldest first. If
+// lexicon is not empty, only rounds in that lexicon count. Up to recent
+// of the latest rounds are included as they are.
+func MakeProfile(user string, rounds []Round, lexicon string,
+	recent int) Profile {
+	p := Profile{User: user, ByLength: make(map[int]*LengthStats),
+		Recent: []Round{}}
+	seconds := 0.0
+	for i := len(rounds) - 1; i >= 0; i-- {
+		r := rounds[i]
+		if lexicon != "" && r.Lexicon != lexicon {
+			continue
+		}
+		if len(p.Recent) < recent {
+			p.Recent = append(p.Recent, r)
+		}
+		p.Rounds++
+		p.Solved += r.Solved
+		p.Missed += r.Missed
+		seconds += r.Seconds
+		for length, lr := range r.ByLength {
+			ls := p.ByLength[length]
+			if ls == nil {
+				ls = &LengthStats{}
+				p.ByLength[length] = ls
+			}
+			ls.Found += lr.Found
+			ls.Total += lr.Total
+		}
+	}
+	for _, ls := range p.ByLength {
+		if ls.Total > 0 {
+			ls.Accuracy = float64(ls.Found) / float64(ls.Total)
+		}
+	}
+	if seconds > 0 {
+		p.SolvesPerMinute = float64(p.Solved) / (seconds / 60)
+	}
+	return p
+}
+
+// Recorder keeps the results of every wordwalls round.
+type Recorder struct {
+	Store Store
+}
+
+func (r Recorder) RecordRound(result *wordwalls.RoundResult) {
+	rounds := make(map[string]Round)
+	for _, player := range result.Players {
+		rounds[player] = Round{
+			Played:       result.Ended,
+			Lexicon:      result.Lexicon,
+			GameType:     result.GameType,
+			PlayerResult: result.PlayerResult(player),
+		}
+	}
+	err := r.Store.Record(rounds)
+	if err != nil {
+		log.Println("[ERROR] Recording stats", err)
+	}
+}
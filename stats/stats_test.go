@@ -0,0 +1,109 @@
+package stats
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/domino14/gosports/wordwalls"
+)
+
+func testResult(end time.Time) *wordwalls.RoundResult {
+	return &wordwalls.RoundResult{
+		Lexicon:  "America",
+		GameType: wordwalls.Regular,
+		Started:  end.Add(-2 * time.Minute),
+		Ended:    end,
+		Questions: []wordwalls.Question{
+			{Question: "AEINRST", Answers: []string{"RETAINS", "STAINER"}},
+			{Question: "ADEINRT", Answers: []string{"TRAINED"}},
+			{Question: "IQ", Answers: []string{"QI"}},
+		},
+		Players: []string{"cesar", "messi"},
+		Solved: map[string][]string{
+			"cesar": {"RETAINS", "QI"},
+			"messi": {"TRAINED"},
+		},
+	}
+}
+
+func TestRecordAndProfile(t *testing.T) {
+	store, _ := NewFileStore("")
+	r := Recorder{Store: store}
+	now := time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)
+	r.RecordRound(testResult(now))
+	second := testResult(now.Add(time.Hour))
+	second.Lexicon = "CSW15"
+	r.RecordRound(second)
+
+	rounds, _ := store.Rounds("cesar")
+	if len(rounds) != 2 || rounds[0].Solved != 2 || rounds[0].Missed != 1 {
+		t.Fatalf("Unexpected rounds %v", rounds)
+	}
+	p := MakeProfile("cesar", rounds, "", 1)
+	if p.Rounds != 2 || p.Solved != 4 || p.Missed != 2 ||
+		p.SolvesPerMinute != 1 {
+		t.Errorf("Unexpected profile %v", p)
+	}
+	if len(p.Recent) != 1 || p.Recent[0].Lexicon != "CSW15" {
+		t.Errorf("Unexpected recent rounds %v", p.Recent)
+	}
+	if p.ByLength[7].Found != 2 || p.ByLength[7].Total != 6 ||
+		p.ByLength[2].Accuracy != 1 {
+		t.Errorf("Unexpected stats by length %v %v", p.ByLength[7],
+			p.ByLength[2])
+	}
+	p = MakeProfile("cesar", rounds, "America", 10)
+	if p.Rounds != 1 {
+		t.Errorf("Should only have counted America rounds, got %v", p)
+	}
+}
+
+func TestFileStoreRecordsEveryone(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "stats.json")
+	store, _ := NewFileStore(path)
+	Recorder{Store: store}.RecordRound(testResult(time.Now()))
+	store, err = NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range []string{"cesar", "messi"} {
+		if rounds, _ := store.Rounds(user); len(rounds) != 1 {
+			t.Errorf("Unexpected rounds for %v: %v", user, rounds)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	store, _ := NewFileStore("")
+	Recorder{Store: store}.RecordRound(testResult(time.Now()))
+	h := Handler{Store: store,
+		LastSession: func(user string) *wordwalls.SessionSummary {
+			return &wordwalls.SessionSummary{User: user, Rounds: 3}
+		}}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/stats/messi", nil))
+	p := Profile{}
+	json.Unmarshal(rec.Body.Bytes(), &p)
+	if rec.Code != 200 || p.User != "messi" || p.Solved != 1 {
+		t.Errorf("Unexpected profile %d %s", rec.Code, rec.Body)
+	}
+	if p.LastSession == nil || p.LastSession.Rounds != 3 {
+		t.Errorf("Profile should include the last session, got %v",
+			p.LastSession)
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/stats/", nil))
+	if rec.Code != 404 {
+		t.Errorf("Expected 404, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,194 @@
+package wordwalls
+
+// This file contains the daily challenges. Every day, at midnight in
+// ChallengeLocation, a new challenge is made for each lexicon and word
+// length. The alphagrams are picked with an RNG seeded from the
+// lexicon, length and date, so every server (and every player) gets
+// the same ones.
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// How many questions are in a daily challenge.
+const ChallengeSize = 50
+
+// How many days of challenges we keep around.
+const challengeRetentionDays = 7
+
+// AlphagramSource lists every alphagram of a given length in a lexicon.
+type AlphagramSource interface {
+	Alphagrams(lexicon string, length int) ([]Question, error)
+}
+
+// ChallengeAlphagrams is where the daily challenges are picked from. If
+// it is nil, no challenges are made.
+var ChallengeAlphagrams AlphagramSource
+
+// ChallengeLocation is the time zone whose midnight starts a new day.
+var ChallengeLocation = time.UTC
+
+// DailyChallenge is a single daily challenge.
+type DailyChallenge struct {
+	ID        int        `json:"id"`
+	Lexicon   string     `json:"lexicon"`
+	Length    int        `json:"length"`
+	Date      string     `json:"date"`
+	Questions []Question `json:"questions"`
+}
+
+type challengePopulation struct {
+	sync.RWMutex
+	challengeMap map[int]*DailyChallenge
+}
+
+var dailyChallenges challengePopulation
+
+func init() {
+	dailyChallenges.reset()
+}
+
+func (c *challengePopulation) reset() {
+	dailyChallenges.challengeMap = make(map[int]*DailyChallenge)
+}
+
+// challengeDate is the day, in ChallengeLocation, that t falls on.
+func challengeDate(t time.Time) string {
+	return t.In(ChallengeLocation).Format("2006-01-02")
+}
+
+func challengeKey(lexicon string, length int, date string) string {
+	return fmt.Sprintf("%s|%d|%s", lexicon, length, date)
+}
+
+// ChallengeID is the id of the challenge for this lexicon, length and
+// date. It is derived from these, so it stays the same across restarts.
+func ChallengeID(lexicon string, length int, date string) int {
+	h := fnv.New32a()
+	h.Write([]byte(challengeKey(lexicon, length, date)))
+	return int(h.Sum32() & 0x7fffffff)
+}
+
+// makeChallenge picks the alphagrams for a challenge.
+func makeChallenge(source AlphagramSource, lexicon string, length int,
+	date string) (*DailyChallenge, error) {
+
+	alphagrams, err := source.Alphagrams(lexicon, length)
+	if err != nil {
+		return nil, err
+	}
+	if len(alphagrams) == 0 {
+		return nil, fmt.Errorf("no %d-letter alphagrams in %v", length,
+			lexicon)
+	}
+	// Sort first so the order the source gives them in doesn't matter.
+	pool := make([]Question, len(alphagrams))
+	copy(pool, alphagrams)
+	sort.Sort(byAlphagram(pool))
+	h := fnv.New64a()
+	h.Write([]byte(challengeKey(lexicon, length, date)))
+	rng := rand.New(rand.NewSource(int64(h.Sum64())))
+	for i := len(pool) - 1; i > 0; i-- {
+		j := rng.Intn(i + 1)
+		pool[i], pool[j] = pool[j], pool[i]
+	}
+	if len(pool) > ChallengeSize {
+		pool = pool[:ChallengeSize]
+	}
+	return &DailyChallenge{
+		ID:        ChallengeID(lexicon, length, date),
+		Lexicon:   lexicon,
+		Length:    length,
+		Date:      date,
+		Questions: pool,
+	}, nil
+}
+
+type byAlphagram []Question
+
+func (q byAlphagram) Len() int           { return len(q) }
+func (q byAlphagram) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q byAlphagram) Less(i, j int) bool { return q[i].Question < q[j].Question }
+
+// generate makes the challenges for the day that t falls on, if they
+// don't exist yet.
+func (c *challengePopulation) generate(source AlphagramSource,
+	lexica []string, lengths []int, t time.Time) {
+	date := challengeDate(t)
+	for _, lexicon := range lexica {
+		for _, length := range lengths {
+			id := ChallengeID(lexicon, length, date)
+			if c.get(id) != nil {
+				continue
+			}
+			challenge, err := makeChallenge(source, lexicon, length, date)
+			if err != nil {
+				log.Println("[ERROR] Making challenge", err)
+				continue
+			}
+			log.Printf("[DEBUG] Made %d-letter %s challenge for %s, id %d",
+				length, lexicon, date, id)
+			c.Lock()
+			c.challengeMap[id] = challenge
+			c.Unlock()
+		}
+	}
+}
+
+// prune deletes challenges that are older than the retention period.
+func (c *challengePopulation) prune(t time.Time) {
+	oldest := challengeDate(t.AddDate(0, 0, -challengeRetentionDays))
+	c.Lock()
+	defer c.Unlock()
+	for id, challenge := range c.challengeMap {
+		if challenge.Date < oldest {
+			delete(c.challengeMap, id)
+		}
+	}
+}
+
+func (c *challengePopulation) get(id int) *DailyChallenge {
+	c.RLock()
+	defer c.RUnlock()
+	return c.challengeMap[id]
+}
+
+// TodaysChallenge returns today's challenge for this lexicon and length,
+// or nil if there isn't one.
+func TodaysChallenge(lexicon string, length int) *DailyChallenge {
+	return dailyChallenges.get(
+		ChallengeID(lexicon, length, challengeDate(time.Now())))
+}
+
+// nextChallengeDay returns the next midnight in ChallengeLocation after t.
+func nextChallengeDay(t time.Time) time.Time {
+	local := t.In(ChallengeLocation)
+	return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0,
+		ChallengeLocation)
+}
+
+// ScheduleDailyChallenges makes today's challenges, and then makes new
+// ones every day at midnight. It does not return, so it should be run
+// in its own goroutine.
+func ScheduleDailyChallenges(lexica []string, lengths []int) {
+	for {
+		now := time.Now()
+		if ChallengeAlphagrams != nil {
+			dailyChallenges.generate(ChallengeAlphagrams, lexica, lengths, now)
+			dailyChallenges.prune(now)
+		}
+		time.Sleep(nextChallengeDay(now).Sub(now))
+	}
+}
+
+// wordList turns the challenge into a list that can be played.
+func (c *DailyChallenge) wordList() *WordList {
+	return NewWordList(c.Lexicon,
+		fmt.Sprintf("%s %ds (%s)", c.Lexicon, c.Length, c.Date), c.Questions)
+}
@@ -0,0 +1,186 @@
+package wordwalls
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/domino14/gosports/leaderboard"
+)
+
+// fakeAlphagrams makes up 200 alphagrams of every length.
+type fakeAlphagrams struct{}
+
+func (f fakeAlphagrams) Alphagrams(lexicon string, length int) ([]Question,
+	error) {
+	if lexicon != "America" {
+		return nil, fmt.Errorf("no such lexicon: %v", lexicon)
+	}
+	questions := []Question{}
+	for i := 0; i < 200; i++ {
+		alpha := fmt.Sprintf("%0*d", length, i)
+		questions = append(questions, Question{Question: alpha,
+			Answers: []string{alpha}})
+	}
+	return questions, nil
+}
+
+func TestChallengesAreTheSameForEveryone(t *testing.T) {
+	c1, err := makeChallenge(fakeAlphagrams{}, "America", 7, "2026-10-19")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, _ := makeChallenge(fakeAlphagrams{}, "America", 7, "2026-10-19")
+	c3, _ := makeChallenge(fakeAlphagrams{}, "America", 7, "2026-10-20")
+	if len(c1.Questions) != ChallengeSize {
+		t.Fatalf("Challenge should have had %v questions", ChallengeSize)
+	}
+	sameAs3 := true
+	for i := range c1.Questions {
+		if c1.Questions[i].Question != c2.Questions[i].Question {
+			t.Errorf("Challenges for the same day should be the same")
+		}
+		if c1.Questions[i].Question != c3.Questions[i].Question {
+			sameAs3 = false
+		}
+	}
+	if sameAs3 {
+		t.Errorf("Challenges for different days should be different")
+	}
+	if c1.ID != ChallengeID("America", 7, "2026-10-19") || c1.ID == c3.ID {
+		t.Errorf("Unexpected challenge ids %v, %v", c1.ID, c3.ID)
+	}
+}
+
+func TestChallengeDayBoundary(t *testing.T) {
+	ChallengeLocation = time.FixedZone("PDT", -7*60*60)
+	defer func() { ChallengeLocation = time.UTC }()
+	// This is still the 18th in PDT.
+	now := time.Date(2026, 10, 19, 6, 30, 0, 0, time.UTC)
+	if challengeDate(now) != "2026-10-18" {
+		t.Errorf("Unexpected date %v", challengeDate(now))
+	}
+	next := nextChallengeDay(now)
+	if !next.Equal(time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected next day %v", next)
+	}
+}
+
+func TestPlayDailyChallenge(t *testing.T) {
+	dailyChallenges.reset()
+	dailyChallenges.generate(fakeAlphagrams{}, []string{"America"},
+		[]int{7, 8}, time.Now())
+	challenge := TodaysChallenge("America", 7)
+	if challenge == nil {
+		t.Fatalf("Should have made today's challenge")
+	}
+	realm := toRealm(tablenum)
+	newTable(realm)
+	st := gameStates.getState(realm)
+	st.options.ChallengeId = challenge.ID
+	joinSitting([]string{"cesar"}, realm)
+	requestStart([]string{"cesar"}, realm)
+	st.Lock()
+	defer st.Unlock()
+	st.cancelTimers()
+	if st.list.Lexicon != "America" ||
+		st.list.OrigQuestions[0].Question != challenge.Questions[0].Question {
+		t.Errorf("Should have been playing today's challenge: %v",
+			st.list.Name)
+	}
+}
+
+func TestChallengeResultsGoToLeaderboard(t *testing.T) {
+	store, _ := leaderboard.NewFileStore("")
+	Leaderboards = store
+	defer func() { Leaderboards = nil }()
+	realm := toRealm(tablenum)
+	newTable(realm)
+	st := gameStates.getState(realm)
+	st.Lock()
+	st.options.TimerSecs = 100
+	st.resetScores()
+	st.startedAt = time.Now().Add(-40 * time.Second)
+	st.scores["cesar"] = 12
+	results := st.challengeResults([]string{"cesar", "messi"}, time.Now())
+	st.Unlock()
+	submitChallengeResults(&RoundResult{challenge: results})
+	top, _ := store.Top(43643, 0)
+	if len(top) != 2 || top[0].User != "cesar" || top[0].Score != 12 ||
+		top[0].TimeRemaining != 60 || top[1].User != "messi" {
+		t.Errorf("Unexpected leaderboard: %v", top)
+	}
+}
+
+func TestClearingChallengeEndsRound(t *testing.T) {
+	store, _ := leaderboard.NewFileStore("")
+	Leaderboards = store
+	defer func() { Leaderboards = nil }()
+	realm := toRealm(tablenum)
+	newTable(realm)
+	joinSitting([]string{"cesar"}, realm)
+	requestStart([]string{"cesar"}, realm)
+	guessWords([]string{"cesar"}, realm)
+	if gameStates.getGameGoing(realm) != GameDone {
+		t.Fatalf("Clearing the challenge should have ended the round")
+	}
+	top, _ := store.Top(43643, 0)
+	// The timer is 270 seconds, and cesar cleared it right away.
+	if len(top) != 1 || top[0].Score != 53 || top[0].TimeRemaining < 260 {
+		t.Errorf("Unexpected leaderboard: %v", top)
+	}
+}
+
+func TestMissedBingosAreRecorded(t *testing.T) {
+	store, _ := leaderboard.NewMissedBingoFile("")
+	MissedBingos = store
+	defer func() { MissedBingos = nil }()
+	st := &gameState{options: &GameOptions{GameType: string(Challenge),
+		ChallengeId: 43643}}
+	st.list = &WordList{Lexicon: "America"}
+	st.list.generateAnswerHash([]Question{
+		{Question: "AEINRST", Answers: []string{"NASTIER", "RETAINS"}},
+		{Question: "AEEINRST", Answers: []string{"ARENITES"}},
+		{Question: "ABC", Answers: []string{"CAB"}},
+	})
+	delete(st.list.answerHash, "ARENITES")
+	delete(st.list.answerHash, "NASTIER")
+	end := time.Now()
+	players := []string{"cesar", "messi"}
+	result := st.roundResult(toRealm(tablenum), players, end)
+	result.challenge = st.challengeResults(players, end)
+	submitChallengeResults(result)
+	missed, _ := store.MostMissed(challengeDate(end), 0)
+	if len(missed) != 1 || missed[0].Alphagram != "AEINRST" ||
+		missed[0].Misses != 2 {
+		t.Errorf("Unexpected missed bingos: %v", missed)
+	}
+}
+
+func TestReplaysDontCountMissedBingos(t *testing.T) {
+	store, _ := leaderboard.NewMissedBingoFile("")
+	MissedBingos = store
+	defer func() { MissedBingos = nil }()
+	entries, _ := leaderboard.NewFileStore("")
+	Leaderboards = entries
+	defer func() { Leaderboards = nil }()
+	st := &gameState{options: &GameOptions{GameType: string(Challenge),
+		ChallengeId: 43643}}
+	st.list = &WordList{Lexicon: "America"}
+	st.list.generateAnswerHash([]Question{
+		{Question: "AEINRST", Answers: []string{"NASTIER", "RETAINS"}},
+	})
+	end := time.Now()
+	result := st.roundResult(toRealm(tablenum), []string{"cesar"}, end)
+	result.challenge = st.challengeResults([]string{"cesar"}, end)
+	submitChallengeResults(result)
+	// cesar plays the challenge again, with messi this time.
+	result = st.roundResult(toRealm(tablenum), []string{"cesar", "messi"},
+		end)
+	result.challenge = st.challengeResults([]string{"cesar", "messi"}, end)
+	submitChallengeResults(result)
+	missed, _ := store.MostMissed(challengeDate(end), 0)
+	if len(missed) != 1 || missed[0].Misses != 2 {
+		t.Errorf("Only first tries should count, got %v", missed)
+	}
+}
@@ -0,0 +1,104 @@
+package wordwalls
+
+// This file tells a player why their guess didn't count, and keeps
+// count of those guesses.
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/domino14/gosports/channels"
+)
+
+const GuessFeedbackMT channels.MessageType = "guessFeedback"
+
+// The reasons a guess can fail.
+const (
+	// Somebody already found the word. In parallel games, only the
+	// guesser's own answers count.
+	GuessAlreadySolved = "solved"
+	// The word is not an answer to anything on the wall.
+	GuessInvalid = "invalid"
+)
+
+// GuessFeedback is sent only to the player whose guess was wrong.
+type GuessFeedback struct {
+	// The normalized guess.
+	Guess  string `json:"guess"`
+	Result string `json:"result"`
+	// Who solved it, for GuessAlreadySolved.
+	By string `json:"by,omitempty"`
+	// The points taken off, if wrong guesses are penalized, and the
+	// guesser's score after that.
+	Penalty   int `json:"penalty,omitempty"`
+	Score     int `json:"score"`
+	TeamScore int `json:"teamScore,omitempty"`
+}
+
+// GuessCounts are a player's guesses in a round that didn't count.
+type GuessCounts struct {
+	// Words that aren't on the wall.
+	Wrong int `json:"wrong"`
+	// Words the player had already found.
+	Repeats int `json:"repeats"`
+	// Words someone else found first.
+	Stolen int `json:"stolen"`
+}
+
+// missed counts a guess that wasn't an answer, and takes points off if
+// the table's scoring rule penalizes wrong guesses. The state must be locked.
+func (s *gameState) missed(guess string, user string) *GuessFeedback {
+	feedback := &GuessFeedback{Guess: guess, Result: GuessInvalid}
+	counts := s.guessCounts[user]
+	by := s.whoSolved(guess, user)
+	switch {
+	case by == user:
+		counts.Repeats++
+	case by != "":
+		counts.Stolen++
+	default:
+		counts.Wrong++
+		feedback.Penalty = s.scorer().Wrong()
+		if feedback.Penalty > 0 {
+			s.scores[user] -= feedback.Penalty
+			if s.options.gameType() == Collaborative {
+				s.teamScore -= feedback.Penalty
+			}
+		}
+	}
+	s.guessCounts[user] = counts
+	if by != "" {
+		feedback.Result = GuessAlreadySolved
+		feedback.By = by
+	}
+	feedback.Score = s.scores[user]
+	if s.options.gameType() == Collaborative {
+		feedback.TeamScore = s.teamScore
+	}
+	return feedback
+}
+
+// whoSolved returns who found the word on the user's wall this round, or
+// "" if no one has. The state must be locked.
+func (s *gameState) whoSolved(word string, user string) string {
+	for _, solve := range s.solves {
+		if solve.Word != word {
+			continue
+		}
+		if s.options.gameType() == Parallel && solve.User != user {
+			continue
+		}
+		return solve.User
+	}
+	return ""
+}
+
+func sendGuessFeedback(table channels.Realm, user string,
+	feedback *GuessFeedback, sender channels.SocketMessageSender) {
+	msg, err := json.Marshal(feedback)
+	if err != nil {
+		log.Println("[ERROR] Marshalling guess feedback", err)
+		return
+	}
+	sender.SendMessage(table, GuessFeedbackMT, string(msg), user)
+}
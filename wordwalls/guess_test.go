@@ -0,0 +1,138 @@
+package wordwalls
+
+import (
+	"testing"
+	"time"
+
+	"github.com/domino14/gosports/channels"
+)
+
+func guessState(gameType GameType) *gameState {
+	st := &gameState{options: &GameOptions{GameType: string(gameType)}}
+	st.setList(&WordList{Lexicon: "FISE"})
+	st.list.generateAnswerHash([]Question{
+		{Question: "AEINRST", Answers: []string{"RETAINS", "STAINER"}},
+		{Question: "ADNUÑ", Answers: []string{"ÑANDU"}},
+	})
+	return st
+}
+
+func TestGuessIsNormalized(t *testing.T) {
+	st := guessState(Collaborative)
+	if answer, _ := st.guess("  retains\n", "cesar"); answer == nil ||
+		answer.Answer != "RETAINS" {
+		t.Errorf("Expected RETAINS, got %v", answer)
+	}
+	// N followed by a combining tilde should match the precomposed Ñ.
+	if answer, _ := st.guess("n\u0303andu", "cesar"); answer == nil ||
+		answer.Answer != "ÑANDU" {
+		t.Errorf("Expected ÑANDU, got %v", answer)
+	}
+	if answer, feedback := st.guess("   ", "cesar"); answer != nil ||
+		feedback != nil {
+		t.Errorf("Blank guesses should be ignored, got %v %v", answer,
+			feedback)
+	}
+}
+
+func TestGuessFeedback(t *testing.T) {
+	st := guessState(Collaborative)
+	st.guess("RETAINS", "cesar")
+	answer, feedback := st.guess("retains", "messi")
+	if answer != nil || feedback == nil ||
+		feedback.Result != GuessAlreadySolved || feedback.By != "cesar" {
+		t.Errorf("Unexpected feedback %v", feedback)
+	}
+	_, feedback = st.guess("nastier", "messi")
+	if feedback == nil || feedback.Result != GuessInvalid ||
+		feedback.Guess != "NASTIER" || feedback.By != "" {
+		t.Errorf("Unexpected feedback %v", feedback)
+	}
+}
+
+func TestGuessFeedbackParallel(t *testing.T) {
+	st := guessState(Parallel)
+	st.setupPlayerWalls([]string{"cesar", "messi"})
+	st.guess("RETAINS", "cesar")
+	// Someone else solving it on their own wall doesn't count.
+	if answer, _ := st.guess("RETAINS", "messi"); answer == nil {
+		t.Errorf("Expected messi to solve RETAINS")
+	}
+	_, feedback := st.guess("RETAINS", "messi")
+	if feedback == nil || feedback.Result != GuessAlreadySolved ||
+		feedback.By != "messi" {
+		t.Errorf("Unexpected feedback %v", feedback)
+	}
+}
+
+func TestGuessCounts(t *testing.T) {
+	st := guessState(Regular)
+	st.guess("RETAINS", "cesar")
+	st.guess("RETAINS", "cesar")
+	st.guess("RETAINS", "messi")
+	st.guess("NASTIER", "messi")
+	st.guess("SATIRE", "messi")
+	summary := st.summary(time.Now())
+	cesar, messi := summary.Guesses["cesar"], summary.Guesses["messi"]
+	if cesar != (GuessCounts{Repeats: 1}) {
+		t.Errorf("Unexpected counts for cesar: %v", cesar)
+	}
+	if messi != (GuessCounts{Wrong: 2, Stolen: 1}) {
+		t.Errorf("Unexpected counts for messi: %v", messi)
+	}
+	// Not penalized unless the table asks for it.
+	if summary.Scores["messi"] != 0 {
+		t.Errorf("Unexpected score %v", summary.Scores["messi"])
+	}
+}
+
+func TestPenalizeWrongGuesses(t *testing.T) {
+	st := guessState(Collaborative)
+	st.options.PenalizeWrong = true
+	st.guess("RETAINS", "cesar")
+	_, feedback := st.guess("NASTIER", "messi")
+	if feedback.Penalty != 1 || feedback.Score != -1 ||
+		feedback.TeamScore != 0 {
+		t.Errorf("Unexpected feedback %v", feedback)
+	}
+	// Guessing a word someone else found isn't penalized.
+	_, feedback = st.guess("RETAINS", "messi")
+	if feedback.Penalty != 0 || feedback.Score != -1 {
+		t.Errorf("Unexpected feedback %v", feedback)
+	}
+	if st.scores["messi"] != -1 || st.teamScore != 0 {
+		t.Errorf("Unexpected scores %v %v", st.scores, st.teamScore)
+	}
+}
+
+func TestWatchersCannotGuessParallel(t *testing.T) {
+	st := guessState(Parallel)
+	st.options.PenalizeWrong = true
+	st.setupPlayerWalls([]string{"cesar"})
+	if answer, feedback := st.guess("NASTIER", "messi"); answer != nil ||
+		feedback != nil {
+		t.Errorf("Unexpected guess result %v %v", answer, feedback)
+	}
+	if _, ok := st.scores["messi"]; ok {
+		t.Errorf("Watcher should not have a score")
+	}
+}
+
+func TestGuessesNotBroadcastInParallel(t *testing.T) {
+	realm := toRealm(tablenum)
+	newTable(realm)
+	guess := channels.Message{Mtype: channels.MessageType(GuessMT)}
+	guess.SetRealm(realm)
+	chat := channels.Message{Mtype: channels.MessageType(ChatMT)}
+	chat.SetRealm(realm)
+	st := gameStates.getState(realm)
+	st.setOptions(&GameOptions{GameType: string(Regular)})
+	if !MessageHandler.ShouldBroadcast(guess) {
+		t.Errorf("Guesses are broadcast in regular games")
+	}
+	st.setOptions(&GameOptions{GameType: string(Parallel)})
+	if MessageHandler.ShouldBroadcast(guess) ||
+		!MessageHandler.ShouldBroadcast(chat) {
+		t.Errorf("Only guesses should be kept from parallel tables")
+	}
+}
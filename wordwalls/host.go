@@ -0,0 +1,269 @@
+package wordwalls
+
+// This file contains the host role for a table. The host is the only
+// one who may kick users, lock the table, or change its settings.
+//
+// The host info lives with the user population rather than the game
+// state, as it has to be read from within the hub's RealmJoin and
+// RealmLeave callbacks, which must never wait on a game state lock.
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+
+	"github.com/domino14/gosports/channels"
+)
+
+var (
+	errNotHost     = errors.New("user is not the host")
+	errKickSelf    = errors.New("the host can't kick themselves")
+	errNotAtTable  = errors.New("user is not at this table")
+	errTableLocked = errors.New("table is locked")
+	errBanned      = errors.New("user was kicked from this table")
+)
+
+// tableInfo holds the host-controlled info for a table.
+type tableInfo struct {
+	host   string
+	locked bool
+	// Users that the host kicked; they can't come back.
+	banned map[string]bool
+}
+
+// table returns the info for a table, creating it if needed. The caller
+// must hold the lock.
+func (u *userPopulation) table(table channels.Realm) *tableInfo {
+	tInfo := u.tables[table]
+	if tInfo == nil {
+		tInfo = &tableInfo{banned: make(map[string]bool)}
+		u.tables[table] = tInfo
+	}
+	return tInfo
+}
+
+// checkEntry returns an error if this user may not join the table.
+// Users already at the table can always open another connection.
+func (u *userPopulation) checkEntry(table channels.Realm,
+	username string) error {
+	u.Lock()
+	defer u.Unlock()
+	tInfo := u.table(table)
+	if tInfo.banned[username] {
+		return errBanned
+	}
+	if _, ok := u.userMap[table][username]; ok {
+		return nil
+	}
+	if tInfo.locked {
+		return errTableLocked
+	}
+	return nil
+}
+
+// reject remembers a connection that checkEntry turned away.
+func (u *userPopulation) reject(connId string) {
+	u.Lock()
+	defer u.Unlock()
+	u.rejected[connId] = true
+}
+
+// wasRejected is true if the connection was turned away when it
+// joined. It forgets about the connection, as it is only asked once the
+// connection is gone.
+func (u *userPopulation) wasRejected(connId string) bool {
+	u.Lock()
+	defer u.Unlock()
+	rejected := u.rejected[connId]
+	delete(u.rejected, connId)
+	return rejected
+}
+
+func (u *userPopulation) host(table channels.Realm) string {
+	u.RLock()
+	defer u.RUnlock()
+	if tInfo, ok := u.tables[table]; ok {
+		return tInfo.host
+	}
+	return ""
+}
+
+// claimHost makes this user the host if the table doesn't have one.
+// Returns true if they became the host.
+func (u *userPopulation) claimHost(table channels.Realm,
+	username string) bool {
+	u.Lock()
+	defer u.Unlock()
+	tInfo := u.table(table)
+	if tInfo.host != "" {
+		return false
+	}
+	log.Printf("[DEBUG] User %s is now the host of table %s\n", username,
+		table)
+	tInfo.host = username
+	return true
+}
+
+// passHost picks a new host if the current one is no longer at the
+// table. Seated users are preferred over watchers; ties are broken
+// alphabetically. Returns the new host, or "" if the host didn't change.
+func (u *userPopulation) passHost(table channels.Realm) string {
+	u.Lock()
+	defer u.Unlock()
+	tInfo, ok := u.tables[table]
+	if !ok {
+		return ""
+	}
+	usersHere := u.userMap[table]
+	if _, ok := usersHere[tInfo.host]; ok {
+		return ""
+	}
+	candidates := []string{}
+	for username := range usersHere {
+		candidates = append(candidates, username)
+	}
+	sort.Strings(candidates)
+	newHost := ""
+	for _, username := range candidates {
+		if usersHere[username].state.isSeated() {
+			newHost = username
+			break
+		}
+	}
+	if newHost == "" && len(candidates) > 0 {
+		newHost = candidates[0]
+	}
+	log.Printf("[DEBUG] Host of table %s passed from %s to %s\n", table,
+		tInfo.host, newHost)
+	tInfo.host = newHost
+	return newHost
+}
+
+// checkHost returns an error unless this connection owns the host's
+// seat.
+func (u *userPopulation) checkHost(table channels.Realm, username string,
+	connId string) error {
+	u.Lock()
+	defer u.Unlock()
+	if u.table(table).host != username {
+		return errNotHost
+	}
+	_, err := u.ownedUser(table, username, connId)
+	return err
+}
+
+// setLocked locks or unlocks the table to new users.
+func (u *userPopulation) setLocked(table channels.Realm, locked bool) {
+	u.Lock()
+	defer u.Unlock()
+	u.table(table).locked = locked
+}
+
+// kick removes a user from the table and bans them from it. Returns
+// the ids of the connections that must be closed.
+func (u *userPopulation) kick(table channels.Realm, host string,
+	username string) ([]string, error) {
+	u.Lock()
+	defer u.Unlock()
+	if host == username {
+		return nil, errKickSelf
+	}
+	usersHere := u.userMap[table]
+	uInfo, ok := usersHere[username]
+	if !ok {
+		return nil, errNotAtTable
+	}
+	connIds := []string{}
+	for connId := range uInfo.connIds {
+		connIds = append(connIds, connId)
+	}
+	delete(usersHere, username)
+	u.table(table).banned[username] = true
+	log.Printf("[DEBUG] User %s was kicked from table %s\n", username, table)
+	return connIds, nil
+}
+
+// handle a command that only the host may give:
+//
+//	kick <user>
+//	lock
+//	unlock
+//	set timer <seconds>
+//	set questions <number>
+func handleHostCommand(fields []string, table channels.Realm, user string,
+	connId string, sender channels.SocketMessageSender) {
+	sendFail := func(errorCode string) {
+		sender.BroadcastMessage(table, FailMT, errorCode)
+	}
+	if err := users.checkHost(table, user, connId); err != nil {
+		log.Printf("[DEBUG] %s can't give host commands: %v\n", user, err)
+		sendFail(FailureNotHost)
+		return
+	}
+	switch fields[0] {
+	case "kick":
+		if len(fields) != 2 {
+			sendFail(FailureBadCommand)
+			return
+		}
+		connIds, err := users.kick(table, user, fields[1])
+		if err != nil {
+			log.Println("[DEBUG] Could not kick:", err)
+			sendFail(FailureBadCommand)
+			return
+		}
+		for _, kickedId := range connIds {
+			sender.Disconnect(table, kickedId, KickedByHost)
+		}
+	case "lock", "unlock":
+		users.setLocked(table, fields[0] == "lock")
+	case "set":
+		if len(fields) != 3 {
+			sendFail(FailureBadCommand)
+			return
+		}
+		handleSettings(fields[1], fields[2], table, sender)
+	}
+}
+
+// Change a setting for this table. Settings can only be changed between
+// rounds.
+func handleSettings(setting string, value string, table channels.Realm,
+	sender channels.SocketMessageSender) {
+	sendFail := func(errorCode string) {
+		sender.BroadcastMessage(table, FailMT, errorCode)
+	}
+	st := gameStates.getState(table)
+	st.Lock()
+	defer st.Unlock()
+	if st.options == nil {
+		sendFail(FailureSettingsDoNotExist)
+		return
+	}
+	if st.going != GameDone {
+		sendFail(FailureGameGoing)
+		return
+	}
+	num, err := strconv.Atoi(value)
+	if err != nil || num <= 0 {
+		sendFail(FailureBadCommand)
+		return
+	}
+	switch setting {
+	case "timer":
+		st.options.TimerSecs = num
+	case "questions":
+		st.options.QuestionsToPull = num
+	default:
+		sendFail(FailureBadCommand)
+		return
+	}
+	msg, err := json.Marshal(st.options)
+	if err != nil {
+		log.Println("[ERROR] Marshalling options", err)
+		return
+	}
+	sender.BroadcastMessage(table, SettingsMT, string(msg))
+}
@@ -0,0 +1,121 @@
+package wordwalls
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHostPassesOnLeave(t *testing.T) {
+	gameStates.reset()
+	users.reset()
+	realm := toRealm(tableName)
+	users.add(realm, "cesar", stSitting, "id1")
+	users.claimHost(realm, "cesar")
+	users.add(realm, "xavi", stWatching, "id2")
+	users.add(realm, "messi", stSitting, "id3")
+	if users.claimHost(realm, "messi") {
+		t.Errorf("messi should not have been able to claim the host")
+	}
+	if newHost := users.passHost(realm); newHost != "" {
+		t.Errorf("Host should not have changed, got %v", newHost)
+	}
+	users.remove(realm, "cesar", "id1")
+	// Seated users come first.
+	if newHost := users.passHost(realm); newHost != "messi" {
+		t.Errorf("Host should have passed to messi, got %v", newHost)
+	}
+	users.remove(realm, "messi", "id3")
+	if newHost := users.passHost(realm); newHost != "xavi" {
+		t.Errorf("Host should have passed to xavi, got %v", newHost)
+	}
+}
+
+func TestKickAndLock(t *testing.T) {
+	gameStates.reset()
+	users.reset()
+	realm := toRealm(tableName)
+	users.add(realm, "cesar", stSitting, "id1")
+	users.claimHost(realm, "cesar")
+	users.add(realm, "messi", stWatching, "id2")
+	if err := users.checkHost(realm, "messi", "id2"); err != errNotHost {
+		t.Errorf("messi should not be the host, got %v", err)
+	}
+	if _, err := users.kick(realm, "cesar", "cesar"); err != errKickSelf {
+		t.Errorf("Host should not be able to kick themselves, got %v", err)
+	}
+	connIds, err := users.kick(realm, "cesar", "messi")
+	if err != nil || len(connIds) != 1 || connIds[0] != "id2" {
+		t.Errorf("Should have kicked id2, got %v, %v", connIds, err)
+	}
+	if err := users.checkEntry(realm, "messi"); err != errBanned {
+		t.Errorf("messi should have been banned, got %v", err)
+	}
+	users.setLocked(realm, true)
+	if err := users.checkEntry(realm, "xavi"); err != errTableLocked {
+		t.Errorf("Table should have been locked, got %v", err)
+	}
+	// The host can still open another tab.
+	if err := users.checkEntry(realm, "cesar"); err != nil {
+		t.Errorf("cesar should have been able to come in, got %v", err)
+	}
+}
+
+func TestHostSettings(t *testing.T) {
+	realm := toRealm(tablenum)
+	sender := newTable(realm)
+	MessageHandler.RealmJoin(realm, "cesar", "id1", true)
+	MessageHandler.RealmJoin(realm, "messi", "id2", false)
+	tableCommand("set timer 60", "messi", realm)
+	if gameStates.timer(realm) != 270 {
+		t.Errorf("messi should not have been able to change the timer")
+	}
+	tableCommand("set timer 60", "cesar", realm)
+	tableCommand("set questions 25", "cesar", realm)
+	tableCommand("set questions -2", "cesar", realm)
+	st := gameStates.getState(realm)
+	if st.options.TimerSecs != 60 || st.options.QuestionsToPull != 25 {
+		t.Errorf("Settings were not changed: %v", st.options)
+	}
+	fails := sender.messages(FailMT)
+	if len(fails) != 2 || fails[0] != FailureNotHost ||
+		fails[1] != FailureBadCommand {
+		t.Errorf("Unexpected failures: %v", fails)
+	}
+	if len(sender.messages(SettingsMT)) != 2 {
+		t.Errorf("Should have broadcast two settings changes")
+	}
+}
+
+func TestBannedUserCannotRejoin(t *testing.T) {
+	realm := toRealm(tablenum)
+	sender := newTable(realm)
+	MessageHandler.RealmJoin(realm, "cesar", "id1", true)
+	MessageHandler.RealmJoin(realm, "messi", "id2", false)
+	tableCommand("kick messi", "cesar", realm)
+	MessageHandler.RealmLeave(realm, "messi", "id2")
+	leaves := func() int {
+		n := 0
+		for _, msg := range sender.messages(PresenceMT) {
+			if strings.Contains(msg, `"event":"leave","user":"messi"`) {
+				n++
+			}
+		}
+		return n
+	}
+	for i := 0; i < 100 && leaves() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// messi tries to come back, and is turned away.
+	MessageHandler.RealmJoin(realm, "messi", "id3", false)
+	MessageHandler.RealmLeave(realm, "messi", "id3")
+	time.Sleep(50 * time.Millisecond)
+	if n := leaves(); n != 1 {
+		t.Errorf("messi's leaving should have been announced once, not %d",
+			n)
+	}
+	if len(users.rejected) != 0 {
+		t.Errorf("Rejected connections should be forgotten once gone: %v",
+			users.rejected)
+	}
+}
@@ -0,0 +1,89 @@
+package wordwalls
+
+// This file lets wordwalls use the lexica loaded in memory by the
+// lexicon package, instead of going to Webolith.
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/domino14/gosports/lexicon"
+)
+
+// LocalLexica is an AlphagramSource for the lexica that are loaded
+// locally.
+type LocalLexica struct{}
+
+func (l LocalLexica) Alphagrams(lexiconName string, length int) ([]Question,
+	error) {
+	lex, ok := lexicon.Get(lexiconName)
+	if !ok {
+		return nil, fmt.Errorf("lexicon %v is not loaded", lexiconName)
+	}
+	return localQuestions(lex, lex.Alphagrams(length)), nil
+}
+
+func localQuestions(lex *lexicon.Lexicon, alphas []string) []Question {
+	questions := make([]Question, len(alphas))
+	for i, alpha := range alphas {
+		questions[i] = Question{
+			Question:    alpha,
+			Answers:     lex.Anagrams(alpha),
+			Probability: lex.Probability(alpha),
+		}
+	}
+	return questions
+}
+
+// ProbabilityList makes a word list of the n most likely alphagrams of
+// the given length, in probability order, e.g. the top 500 7s.
+func ProbabilityList(lexiconName string, length int, n int) (*WordList,
+	error) {
+	lex, ok := lexicon.Get(lexiconName)
+	if !ok {
+		return nil, fmt.Errorf("lexicon %v is not loaded", lexiconName)
+	}
+	questions := localQuestions(lex, lex.TopByProbability(length, n))
+	name := fmt.Sprintf("%s %ds by probability (1-%d)", lexiconName, length,
+		len(questions))
+	return NewWordList(lexiconName, name, questions), nil
+}
+
+// Turn the raw alphagrams into full question objects. If we have the
+// lexicon loaded locally, we don't need to ask Webolith.
+func fullQuestionInfo(w WebolithCommunicator, questions []Question,
+	lexiconName string) ([]byte, error) {
+	lex, ok := lexicon.Get(lexiconName)
+	if !ok {
+		return getFullQInfo(w, questions, lexiconName)
+	}
+	log.Println("[DEBUG] Getting full questions from local lexicon",
+		lexiconName)
+	alphas := make([]string, len(questions))
+	for i, q := range questions {
+		alphas[i] = q.Question
+	}
+	return lex.FullQuestionsJSON(alphas)
+}
+
+// withWordInfo fills in the word info for the questions from the full
+// questions, whether they came from Webolith or a local lexicon.
+func withWordInfo(questions []Question, fullQuestions []byte) []Question {
+	full := []lexicon.FullQuestion{}
+	err := json.Unmarshal(fullQuestions, &full)
+	if err != nil {
+		log.Println("[ERROR] Unmarshalling full questions", err)
+		return questions
+	}
+	words := make(map[string][]lexicon.FullAnswer)
+	for _, fq := range full {
+		words[fq.Question] = fq.Answers
+	}
+	withInfo := make([]Question, len(questions))
+	for i, q := range questions {
+		withInfo[i] = q
+		withInfo[i].Words = words[q.Question]
+	}
+	return withInfo
+}
@@ -0,0 +1,55 @@
+package wordwalls
+
+// This file contains the messages that let clients know who is at a
+// table and what they are doing.
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/domino14/gosports/channels"
+)
+
+const (
+	PresenceJoin  = "join"
+	PresenceLeave = "leave"
+	PresenceState = "state"
+)
+
+// Presence describes a single user at a table. It is broadcast as an
+// event when someone joins, leaves or changes state, and a list of
+// these (without the event) makes up the roster.
+type Presence struct {
+	Event string `json:"event,omitempty"`
+	User  string `json:"user"`
+	State string `json:"state"`
+	Conns int    `json:"conns"`
+	Host  bool   `json:"host,omitempty"`
+}
+
+type byUser []Presence
+
+func (p byUser) Len() int           { return len(p) }
+func (p byUser) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p byUser) Less(i, j int) bool { return p[i].User < p[j].User }
+
+func broadcastPresence(table channels.Realm, presence Presence,
+	sender channels.SocketMessageSender) {
+	msg, err := json.Marshal(presence)
+	if err != nil {
+		log.Println("[ERROR] Marshalling presence", presence, err)
+		return
+	}
+	sender.BroadcastMessage(table, PresenceMT, string(msg))
+}
+
+// Send the full roster to a user who just joined.
+func sendRoster(table channels.Realm, user string, roster []Presence,
+	sender channels.SocketMessageSender) {
+	msg, err := json.Marshal(roster)
+	if err != nil {
+		log.Println("[ERROR] Marshalling roster", roster, err)
+		return
+	}
+	sender.SendMessage(table, RosterMT, string(msg), user)
+}
@@ -0,0 +1,147 @@
+package wordwalls
+
+// This file hands the results of each round to the parts of the server
+// that keep track of how users are doing, such as their cardboxes.
+
+import (
+	"time"
+
+	"github.com/domino14/gosports/channels"
+)
+
+// RoundResult is what happened in a round that was just played.
+type RoundResult struct {
+	Table    channels.Realm
+	Lexicon  string
+	GameType GameType
+	Started  time.Time
+	Ended    time.Time
+	// The questions asked this round.
+	Questions []Question
+	Players   []string
+	// The words each user solved, and every solve in the order they
+	// were found.
+	Solved map[string][]string
+	Solves []Solve
+	// The alphagrams each player left with answers still unfound. In
+	// parallel games this is their own wall; otherwise it is the
+	// table's.
+	Missed map[string][]string
+	// Set for daily challenge rounds.
+	challenge *challengeResults
+}
+
+// LengthResult counts the answers of one word length.
+type LengthResult struct {
+	Found int `json:"found"`
+	Total int `json:"total"`
+}
+
+// PlayerResult is how one player did in a round.
+type PlayerResult struct {
+	// The words the player found, and the ones that no one found on
+	// their wall.
+	Solved   int                   `json:"solved"`
+	Missed   int                   `json:"missed"`
+	ByLength map[int]*LengthResult `json:"byLength"`
+	Seconds  float64               `json:"seconds"`
+}
+
+// PlayerResult works out how one player did in the round.
+func (r *RoundResult) PlayerResult(player string) PlayerResult {
+	pr := PlayerResult{
+		ByLength: make(map[int]*LengthResult),
+		Seconds:  r.Ended.Sub(r.Started).Seconds(),
+	}
+	mine := make(map[string]bool)
+	for _, word := range r.Solved[player] {
+		mine[word] = true
+	}
+	// The words found on this player's wall, by anyone who shares it.
+	found := mine
+	if r.GameType != Parallel {
+		found = make(map[string]bool)
+		for _, words := range r.Solved {
+			for _, word := range words {
+				found[word] = true
+			}
+		}
+	}
+	for _, q := range r.Questions {
+		length := len([]rune(q.Question))
+		lr := pr.ByLength[length]
+		if lr == nil {
+			lr = &LengthResult{}
+			pr.ByLength[length] = lr
+		}
+		for _, word := range q.Answers {
+			lr.Total++
+			if mine[word] {
+				lr.Found++
+				pr.Solved++
+			}
+			if !found[word] {
+				pr.Missed++
+			}
+		}
+	}
+	return pr
+}
+
+// RoundRecorder keeps track of round results.
+type RoundRecorder interface {
+	RecordRound(result *RoundResult)
+}
+
+// Cardboxes moves the alphagrams asked in each round between users'
+// cardboxes. If it is nil, rounds don't affect cardboxes.
+var Cardboxes RoundRecorder
+
+// Stats keeps every player's round results, for their profile. If it
+// is nil, they are not kept.
+var Stats RoundRecorder
+
+// roundResult sums up the round for the recorders. The state must be
+// locked.
+func (s *gameState) roundResult(table channels.Realm, players []string,
+	end time.Time) *RoundResult {
+	result := &RoundResult{
+		Table:     table,
+		Lexicon:   s.list.Lexicon,
+		GameType:  s.options.gameType(),
+		Started:   s.startedAt,
+		Ended:     end,
+		Questions: s.roundQuestions,
+		Players:   players,
+		Solved:    make(map[string][]string),
+		Solves:    append([]Solve{}, s.solves...),
+		Missed:    make(map[string][]string),
+	}
+	for user, words := range s.solved {
+		result.Solved[user] = append([]string{}, words...)
+	}
+	for _, player := range players {
+		answerHash := s.list.answerHash
+		if hash, ok := s.playerHashes[player]; ok {
+			answerHash = hash
+		}
+		result.Missed[player] = missedAlphagrams(answerHash)
+	}
+	return result
+}
+
+// recordRound gives the round's results to everything that keeps track
+// of them. The recorders may write to disk, so this should be called
+// without the state locked.
+func recordRound(result *RoundResult) {
+	if result == nil {
+		return
+	}
+	if Cardboxes != nil {
+		Cardboxes.RecordRound(result)
+	}
+	if Stats != nil {
+		Stats.RecordRound(result)
+	}
+	submitChallengeResults(result)
+}
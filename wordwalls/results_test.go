@@ -0,0 +1,123 @@
+package wordwalls
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingRecorder struct {
+	sync.Mutex
+	results []*RoundResult
+}
+
+func (r *recordingRecorder) RecordRound(result *RoundResult) {
+	// This would deadlock if the table were still locked.
+	gameStates.getGameGoing(result.Table)
+	r.Lock()
+	defer r.Unlock()
+	r.results = append(r.results, result)
+}
+
+func TestRoundResults(t *testing.T) {
+	recorder := &recordingRecorder{}
+	Cardboxes = recorder
+	defer func() { Cardboxes = nil }()
+	realm := toRealm(tablenum)
+	newTable(realm)
+	gameStates.getState(realm).options.GameType = string(Collaborative)
+	userlist := []string{"cesar", "messi"}
+	joinSitting(userlist, realm)
+	requestStart(userlist, realm)
+	guessWords(userlist, realm)
+
+	recorder.Lock()
+	defer recorder.Unlock()
+	if len(recorder.results) != 1 {
+		t.Fatalf("Should have recorded one round, got %v", recorder.results)
+	}
+	result := recorder.results[0]
+	if len(result.Questions) != 50 || len(result.Players) != 2 ||
+		result.GameType != Collaborative || result.Started.IsZero() {
+		t.Errorf("Unexpected result %v", result)
+	}
+	solved := 0
+	for _, player := range userlist {
+		solved += len(result.Solved[player])
+		if len(result.Missed[player]) != 0 {
+			t.Errorf("%v should not have missed anything, got %v", player,
+				result.Missed[player])
+		}
+	}
+	if solved != 53 {
+		t.Errorf("Should have solved 53 words, got %v", solved)
+	}
+	// The word info comes from Webolith's full questions.
+	for _, q := range result.Questions {
+		if len(q.Words) == 0 || q.Words[0].Definition == "" {
+			t.Errorf("Question %v should have word info", q.Question)
+			break
+		}
+	}
+	if len(result.Solves) != 53 {
+		t.Fatalf("Should have timed 53 solves, got %v", len(result.Solves))
+	}
+	for _, solve := range result.Solves {
+		// The guesses came in a second after the countdown.
+		if solve.Elapsed < 500 || solve.Elapsed > 5000 {
+			t.Errorf("Unexpected solve time %v", solve)
+		}
+	}
+}
+
+func TestPlayerResult(t *testing.T) {
+	result := &RoundResult{
+		GameType: Regular,
+		Questions: []Question{
+			{Question: "AEINRST", Answers: []string{"RETAINS", "STAINER"}},
+			{Question: "IQ", Answers: []string{"QI"}},
+		},
+		Solved: map[string][]string{"cesar": {"RETAINS"}, "messi": {"QI"}},
+	}
+	pr := result.PlayerResult("cesar")
+	if pr.Solved != 1 || pr.Missed != 1 || pr.ByLength[7].Total != 2 ||
+		pr.ByLength[2].Found != 0 {
+		t.Errorf("Unexpected result %v", pr)
+	}
+	// In parallel games, what others found doesn't count.
+	result.GameType = Parallel
+	if pr := result.PlayerResult("cesar"); pr.Missed != 2 {
+		t.Errorf("Unexpected parallel result %v", pr)
+	}
+}
+
+func TestSessionSummaryOnLeave(t *testing.T) {
+	realm := toRealm(tablenum)
+	sender := newTable(realm)
+	MessageHandler.RealmJoin(realm, "cesar", "id1", true)
+	MessageHandler.RealmJoin(realm, "cesar", "id2", false)
+	sessions.record(&RoundResult{
+		Table:     realm,
+		Questions: []Question{{Question: "IQ", Answers: []string{"QI"}}},
+		Players:   []string{"cesar"},
+		Solved:    map[string][]string{"cesar": {"QI"}},
+	})
+	MessageHandler.RealmLeave(realm, "cesar", "id2")
+	MessageHandler.RealmLeave(realm, "cesar", "id1")
+	var summaries []string
+	for i := 0; i < 100; i++ {
+		if summaries = sender.messages(SessionMT); len(summaries) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(summaries) != 1 ||
+		summaries[0] != `{"user":"cesar","rounds":1,"solved":1,"missed":0,`+
+			`"byLength":{"2":{"found":1,"total":1}},"seconds":0}` {
+		t.Errorf("Unexpected session summaries %v", summaries)
+	}
+	// cesar's connection is gone, so the summary is kept for them.
+	if last := LastSession("cesar"); last == nil || last.Rounds != 1 {
+		t.Errorf("Unexpected last session %v", last)
+	}
+}
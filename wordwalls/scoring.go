@@ -0,0 +1,146 @@
+package wordwalls
+
+// This file has the rules for how many points a guess is worth.
+
+import (
+	"fmt"
+	"log"
+	"time"
+	"unicode/utf8"
+)
+
+// The scoring rules a table can pick in its game options.
+const (
+	// One point for every answer. This is the default, and the only
+	// rule for challenges.
+	ScoringPerWord = "word"
+	// One point for every letter in the answer.
+	ScoringByLength = "length"
+	// One point for every answer, and a bonus point for being the
+	// first at the table to find an answer to an alphagram.
+	ScoringFirstSolve = "first"
+	// One point for every answer, and up to MaxSpeedBonus more the
+	// earlier in the round it was found.
+	ScoringSpeed = "speed"
+)
+
+// MaxSpeedBonus is the bonus for an answer found right as the round
+// starts. It goes down to nothing as the timer runs out.
+const MaxSpeedBonus = 2
+
+// WrongGuessPenalty is what a wrong guess costs with negative marking.
+const WrongGuessPenalty = 1
+
+// ScoredAnswer is what a Scorer gets to know about a correct answer.
+type ScoredAnswer struct {
+	Word      string
+	Alphagram string
+	// How far into the round the answer was found, and how long the
+	// round is.
+	Elapsed time.Duration
+	Timer   time.Duration
+	// Whether no one at the table had found an answer to this alphagram
+	// yet.
+	FirstSolve bool
+}
+
+// Scorer decides what guesses are worth.
+type Scorer interface {
+	// Answer returns the points for a correct answer.
+	Answer(a ScoredAnswer) int
+	// Wrong returns the points taken off for a guess that isn't on the
+	// wall.
+	Wrong() int
+}
+
+type perWord struct{}
+
+func (perWord) Answer(a ScoredAnswer) int { return 1 }
+func (perWord) Wrong() int                { return 0 }
+
+type byLength struct{}
+
+func (byLength) Answer(a ScoredAnswer) int {
+	return utf8.RuneCountInString(a.Word)
+}
+func (byLength) Wrong() int { return 0 }
+
+type firstSolve struct{}
+
+func (firstSolve) Answer(a ScoredAnswer) int {
+	if a.FirstSolve {
+		return 2
+	}
+	return 1
+}
+func (firstSolve) Wrong() int { return 0 }
+
+type speedBonus struct{}
+
+func (speedBonus) Answer(a ScoredAnswer) int {
+	if a.Timer <= 0 || a.Elapsed >= a.Timer {
+		return 1
+	}
+	// The bonus is rounded to the nearest point.
+	left := a.Timer - a.Elapsed
+	return 1 + int((left*MaxSpeedBonus+a.Timer/2)/a.Timer)
+}
+func (speedBonus) Wrong() int { return 0 }
+
+// negativeMarking takes points off for wrong guesses on top of another
+// rule.
+type negativeMarking struct {
+	Scorer
+}
+
+func (negativeMarking) Wrong() int { return WrongGuessPenalty }
+
+// scorer returns the scoring rule the options ask for. Challenges are
+// always scored one point per word, so that everyone on a challenge's
+// leaderboard is scored the same way.
+func (o *GameOptions) scorer() (Scorer, error) {
+	if o == nil || o.gameType() == Challenge {
+		return perWord{}, nil
+	}
+	var scorer Scorer
+	switch o.Scoring {
+	case "", ScoringPerWord:
+		scorer = perWord{}
+	case ScoringByLength:
+		scorer = byLength{}
+	case ScoringFirstSolve:
+		scorer = firstSolve{}
+	case ScoringSpeed:
+		scorer = speedBonus{}
+	default:
+		return nil, fmt.Errorf("unknown scoring rule %q", o.Scoring)
+	}
+	if o.PenalizeWrong {
+		scorer = negativeMarking{scorer}
+	}
+	return scorer, nil
+}
+
+// scorer is the table's scoring rule. Rounds don't start with a rule we
+// don't know (see startGame), so falling back to one point per word
+// only happens if the options were changed during the round. The state
+// must be locked.
+func (s *gameState) scorer() Scorer {
+	scorer, err := s.options.scorer()
+	if err != nil {
+		log.Println("[ERROR] Scoring a guess:", err)
+		return perWord{}
+	}
+	return scorer
+}
+
+// firstSolve is true if no one has found an answer to the alphagram this
+// round. The state must be locked.
+func (s *gameState) firstSolve(alphagram string) bool {
+	for _, solve := range s.solves {
+		if solve.Alphagram == alphagram {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,158 @@
+package wordwalls
+
+import (
+	"testing"
+	"time"
+)
+
+func scoringState(gameType GameType, scoring string) *gameState {
+	st := guessState(gameType)
+	st.options.Scoring = scoring
+	st.options.TimerSecs = 100
+	st.startedAt = time.Now()
+	return st
+}
+
+func TestScoringPerWord(t *testing.T) {
+	st := scoringState(Collaborative, "")
+	st.guess("RETAINS", "cesar")
+	answer, _ := st.guess("ÑANDU", "messi")
+	if answer.Points != 1 || answer.Score != 1 || answer.TeamScore != 2 {
+		t.Errorf("Unexpected answer %v", answer)
+	}
+	_, feedback := st.guess("NASTIER", "messi")
+	if feedback.Penalty != 0 || st.scores["messi"] != 1 {
+		t.Errorf("Unexpected feedback %v", feedback)
+	}
+}
+
+func TestScoringByLength(t *testing.T) {
+	st := scoringState(Regular, ScoringByLength)
+	st.guess("RETAINS", "cesar")
+	// Ñ is one letter, even though it's two bytes.
+	answer, _ := st.guess("ÑANDU", "cesar")
+	if answer.Points != 5 || answer.Score != 12 {
+		t.Errorf("Unexpected answer %v", answer)
+	}
+}
+
+func TestScoringFirstSolve(t *testing.T) {
+	st := scoringState(Regular, ScoringFirstSolve)
+	answer, _ := st.guess("RETAINS", "cesar")
+	if answer.Points != 2 {
+		t.Errorf("Unexpected answer %v", answer)
+	}
+	answer, _ = st.guess("STAINER", "messi")
+	if answer.Points != 1 || answer.Score != 1 {
+		t.Errorf("Unexpected answer %v", answer)
+	}
+}
+
+func TestScoringFirstSolveParallel(t *testing.T) {
+	st := scoringState(Parallel, ScoringFirstSolve)
+	st.setupPlayerWalls([]string{"cesar", "messi"})
+	st.guess("RETAINS", "cesar")
+	// The bonus is for being first at the table, not on your own wall.
+	answer, _ := st.guess("STAINER", "messi")
+	if answer.Points != 1 {
+		t.Errorf("Unexpected answer %v", answer)
+	}
+	answer, _ = st.guess("ÑANDU", "messi")
+	if answer.Points != 2 || answer.Score != 3 {
+		t.Errorf("Unexpected answer %v", answer)
+	}
+}
+
+func TestScoringSpeed(t *testing.T) {
+	st := scoringState(Regular, ScoringSpeed)
+	answer, _ := st.guess("RETAINS", "cesar")
+	if answer.Points != 1+MaxSpeedBonus {
+		t.Errorf("Unexpected answer %v", answer)
+	}
+	// 60 of 100 seconds in, there's 40% of the bonus left, which
+	// rounds to a point.
+	st.startedAt = time.Now().Add(-60 * time.Second)
+	answer, _ = st.guess("STAINER", "cesar")
+	if answer.Points != 2 {
+		t.Errorf("Unexpected answer %v", answer)
+	}
+	st.startedAt = time.Now().Add(-80 * time.Second)
+	answer, _ = st.guess("ÑANDU", "cesar")
+	if answer.Points != 1 || answer.Score != 6 {
+		t.Errorf("Unexpected answer %v", answer)
+	}
+}
+
+func TestSpeedBonus(t *testing.T) {
+	scorer := speedBonus{}
+	for _, tc := range []struct {
+		elapsed, timer time.Duration
+		points         int
+	}{
+		{0, 100 * time.Second, 3},
+		{20 * time.Second, 100 * time.Second, 3},
+		{75 * time.Second, 100 * time.Second, 2},
+		{76 * time.Second, 100 * time.Second, 1},
+		{100 * time.Second, 100 * time.Second, 1},
+		{120 * time.Second, 100 * time.Second, 1},
+		{10 * time.Second, 0, 1},
+	} {
+		points := scorer.Answer(ScoredAnswer{Elapsed: tc.elapsed,
+			Timer: tc.timer})
+		if points != tc.points {
+			t.Errorf("Expected %v points for %v of %v, got %v", tc.points,
+				tc.elapsed, tc.timer, points)
+		}
+	}
+}
+
+func TestNegativeMarking(t *testing.T) {
+	st := scoringState(Collaborative, ScoringByLength)
+	st.options.PenalizeWrong = true
+	st.guess("RETAINS", "cesar")
+	_, feedback := st.guess("NASTIER", "cesar")
+	if feedback.Penalty != WrongGuessPenalty || feedback.Score != 6 ||
+		feedback.TeamScore != 6 {
+		t.Errorf("Unexpected feedback %v", feedback)
+	}
+	// Repeats and stolen answers aren't wrong.
+	st.guess("RETAINS", "cesar")
+	st.guess("RETAINS", "messi")
+	if st.scores["cesar"] != 6 || st.scores["messi"] != 0 ||
+		st.teamScore != 6 {
+		t.Errorf("Unexpected scores %v %v", st.scores, st.teamScore)
+	}
+}
+
+func TestChallengesScorePerWord(t *testing.T) {
+	st := scoringState(Challenge, ScoringByLength)
+	st.options.PenalizeWrong = true
+	answer, _ := st.guess("RETAINS", "cesar")
+	if answer.Points != 1 {
+		t.Errorf("Unexpected answer %v", answer)
+	}
+	_, feedback := st.guess("NASTIER", "cesar")
+	if feedback.Penalty != 0 || st.scores["cesar"] != 1 {
+		t.Errorf("Challenges should not penalize wrong guesses, got %v",
+			feedback)
+	}
+}
+
+func TestUnknownScoringRule(t *testing.T) {
+	options := &GameOptions{GameType: string(Regular), Scoring: "bogus"}
+	if _, err := options.scorer(); err == nil {
+		t.Errorf("Should not know the bogus scoring rule")
+	}
+	realm := toRealm(tablenum)
+	sender := newTable(realm)
+	st := gameStates.getState(realm)
+	st.setOptions(&GameOptions{GameType: string(Regular), Scoring: "bogus",
+		TimerSecs: 100, QuestionsToPull: 50, WordListID: 22447})
+	joinSitting([]string{"cesar"}, realm)
+	tableCommand("start", "cesar", realm)
+	fails := sender.messages(FailMT)
+	if len(fails) != 1 || fails[0] != FailureBadScoring ||
+		gameStates.getGameGoing(realm) != GameDone {
+		t.Errorf("Unexpected failures: %v", fails)
+	}
+}
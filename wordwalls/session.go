@@ -0,0 +1,118 @@
+package wordwalls
+
+// This file keeps a running total of how each player has done since
+// they sat down at a table, and sums it up for the table when they
+// leave. The player's own connection is gone by then, so their last
+// session is kept for the stats API as well.
+//
+// Like the host info, sessions are kept apart from the game state, as
+// they are read from within the hub's RealmLeave callback.
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/domino14/gosports/channels"
+)
+
+// SessionSummary is how a player did over all the rounds they played
+// at a table.
+type SessionSummary struct {
+	User     string                `json:"user"`
+	Rounds   int                   `json:"rounds"`
+	Solved   int                   `json:"solved"`
+	Missed   int                   `json:"missed"`
+	ByLength map[int]*LengthResult `json:"byLength"`
+	Seconds  float64               `json:"seconds"`
+}
+
+func (s *SessionSummary) add(pr PlayerResult) {
+	s.Rounds++
+	s.Solved += pr.Solved
+	s.Missed += pr.Missed
+	s.Seconds += pr.Seconds
+	for length, lr := range pr.ByLength {
+		total := s.ByLength[length]
+		if total == nil {
+			total = &LengthResult{}
+			s.ByLength[length] = total
+		}
+		total.Found += lr.Found
+		total.Total += lr.Total
+	}
+}
+
+type sessionPopulation struct {
+	sync.Mutex
+	sessions map[channels.Realm]map[string]*SessionSummary
+	// The last session each user finished, at any table.
+	last map[string]*SessionSummary
+}
+
+var sessions sessionPopulation
+
+func init() {
+	sessions.reset()
+}
+
+func (sp *sessionPopulation) reset() {
+	sp.Lock()
+	defer sp.Unlock()
+	sp.sessions = make(map[channels.Realm]map[string]*SessionSummary)
+	sp.last = make(map[string]*SessionSummary)
+}
+
+// record adds a round to each player's session.
+func (sp *sessionPopulation) record(result *RoundResult) {
+	sp.Lock()
+	defer sp.Unlock()
+	table := sp.sessions[result.Table]
+	if table == nil {
+		table = make(map[string]*SessionSummary)
+		sp.sessions[result.Table] = table
+	}
+	for _, player := range result.Players {
+		session := table[player]
+		if session == nil {
+			session = &SessionSummary{User: player,
+				ByLength: make(map[int]*LengthResult)}
+			table[player] = session
+		}
+		session.add(result.PlayerResult(player))
+	}
+}
+
+// end ends a user's session, and returns it if they played any rounds.
+func (sp *sessionPopulation) end(table channels.Realm,
+	user string) *SessionSummary {
+	sp.Lock()
+	defer sp.Unlock()
+	session := sp.sessions[table][user]
+	delete(sp.sessions[table], user)
+	if len(sp.sessions[table]) == 0 {
+		delete(sp.sessions, table)
+	}
+	if session != nil {
+		sp.last[user] = session
+	}
+	return session
+}
+
+// LastSession returns the last session the user finished at a table,
+// or nil if they haven't finished one since the server started.
+func LastSession(user string) *SessionSummary {
+	sessions.Lock()
+	defer sessions.Unlock()
+	return sessions.last[user]
+}
+
+func broadcastSession(table channels.Realm, session *SessionSummary,
+	sender channels.SocketMessageSender) {
+	msg, err := json.Marshal(session)
+	if err != nil {
+		log.Println("[ERROR] Marshalling session", session, err)
+		return
+	}
+	sender.BroadcastMessage(table, SessionMT, string(msg))
+}
@@ -0,0 +1,84 @@
+package wordwalls
+
+// This file lets the host load a list that someone shared with them
+// into the table. The list is played from wherever its owner left off,
+// and nothing that happens at the table is saved back to it.
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/domino14/gosports/channels"
+)
+
+const FailureCannotLoadList = "CANNOT_LOAD_LIST"
+
+const ListMT channels.MessageType = "list"
+
+// SharedListLoader loads a word list that was shared with a user, given
+// the code from its share link.
+type SharedListLoader interface {
+	LoadShared(user string, code string) (*WordList, error)
+}
+
+// SharedLists is where shared lists are loaded from. If it is nil,
+// shared lists can't be loaded.
+var SharedLists SharedListLoader
+
+// LoadedList tells the table which list it will play next.
+type LoadedList struct {
+	Name             string `json:"name"`
+	Lexicon          string `json:"lexicon"`
+	NumAlphagrams    int    `json:"numAlphagrams"`
+	NumCurAlphagrams int    `json:"numCurAlphagrams"`
+	LoadedBy         string `json:"loadedBy"`
+}
+
+// handle a load command:
+//
+//	load <share code>
+func handleLoadShared(fields []string, table channels.Realm, user string,
+	connId string, sender channels.SocketMessageSender) {
+	sendFail := func(errorCode string) {
+		sender.BroadcastMessage(table, FailMT, errorCode)
+	}
+	if len(fields) != 2 {
+		sendFail(FailureBadCommand)
+		return
+	}
+	if err := users.checkHost(table, user, connId); err != nil {
+		log.Printf("[DEBUG] %s can't load lists: %v\n", user, err)
+		sendFail(FailureNotHost)
+		return
+	}
+	if SharedLists == nil {
+		sendFail(FailureCannotLoadList)
+		return
+	}
+	st := gameStates.getState(table)
+	st.Lock()
+	defer st.Unlock()
+	if st.going != GameDone {
+		sendFail(FailureGameGoing)
+		return
+	}
+	list, err := SharedLists.LoadShared(user, fields[1])
+	if err != nil {
+		log.Println("[ERROR] Loading shared list", err)
+		sendFail(FailureCannotLoadList)
+		return
+	}
+	st.loadedList = list
+	msg, err := json.Marshal(LoadedList{
+		Name:             list.Name,
+		Lexicon:          list.Lexicon,
+		NumAlphagrams:    list.NumQuestions,
+		NumCurAlphagrams: list.NumCurAlphagrams,
+		LoadedBy:         user,
+	})
+	if err != nil {
+		log.Println("[ERROR] Marshalling loaded list", err)
+		return
+	}
+	sender.BroadcastMessage(table, ListMT, string(msg))
+}
@@ -0,0 +1,102 @@
+package wordwalls
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type fakeSharedLists struct{}
+
+func (f fakeSharedLists) LoadShared(user string, code string) (*WordList,
+	error) {
+	if code != "abc123" || user != "cesar" {
+		return nil, errors.New("not shared with this user")
+	}
+	return NewWordList("America", "Shared sevens", []Question{
+		{Question: "AEINRST", Answers: []string{"RETAINS", "STAINER"}},
+	}), nil
+}
+
+func TestLoadSharedList(t *testing.T) {
+	SharedLists = fakeSharedLists{}
+	defer func() { SharedLists = nil }()
+	realm := toRealm(tablenum)
+	sender := newTable(realm)
+	MessageHandler.RealmJoin(realm, "cesar", "id1", true)
+	MessageHandler.RealmJoin(realm, "messi", "id2", false)
+	tableCommand("load abc123", "messi", realm)
+	tableCommand("load nope", "cesar", realm)
+	tableCommand("load abc123", "cesar", realm)
+	fails := sender.messages(FailMT)
+	if len(fails) != 2 || fails[0] != FailureNotHost ||
+		fails[1] != FailureCannotLoadList {
+		t.Errorf("Unexpected failures: %v", fails)
+	}
+	loaded := sender.messages(ListMT)
+	if len(loaded) != 1 {
+		t.Fatalf("Should have broadcast the loaded list, got %v", loaded)
+	}
+	info := LoadedList{}
+	if err := json.Unmarshal([]byte(loaded[0]), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "Shared sevens" || info.NumAlphagrams != 1 ||
+		info.LoadedBy != "cesar" {
+		t.Errorf("Unexpected loaded list %v", info)
+	}
+	tableCommand("start", "cesar", realm)
+	st := gameStates.getState(realm)
+	st.RLock()
+	defer st.RUnlock()
+	if st.list == nil || st.list.Name != "Shared sevens" {
+		t.Errorf("Should have started with the shared list, got %v", st.list)
+	}
+	st.cancelCountdown()
+}
+
+func TestLoadedListRunsOut(t *testing.T) {
+	SharedLists = fakeSharedLists{}
+	defer func() { SharedLists = nil }()
+	realm := toRealm(tablenum)
+	sender := newTable(realm)
+	MessageHandler.RealmJoin(realm, "cesar", "id1", true)
+	tableCommand("load abc123", "cesar", realm)
+	st := gameStates.getState(realm)
+	st.Lock()
+	// As if the only question had been played last round.
+	st.loadedList.QuestionIndex = 1
+	st.Unlock()
+	tableCommand("start", "cesar", realm)
+	fails := sender.messages(FailMT)
+	if len(fails) != 1 || fails[0] != FailureListDone {
+		t.Errorf("Unexpected failures: %v", fails)
+	}
+	st.RLock()
+	defer st.RUnlock()
+	if st.loadedList != nil || st.going != GameDone {
+		t.Errorf("The table should be done with the loaded list")
+	}
+}
+
+func TestNextSetRunsOut(t *testing.T) {
+	list := NewWordList("America", "Threes", []Question{
+		{Question: "ACT", Answers: []string{"CAT"}},
+		{Question: "AET", Answers: []string{"EAT", "TAE", "TEA"}},
+		{Question: "ART", Answers: []string{"RAT", "TAR"}},
+	})
+	if qs := list.nextSet(2); len(qs) != 2 || list.done() {
+		t.Errorf("Unexpected first set %v", qs)
+	}
+	if qs := list.nextSet(2); len(qs) != 1 || list.QuestionIndex != 3 ||
+		!list.done() {
+		t.Errorf("Unexpected second set %v", qs)
+	}
+	if qs := list.nextSet(2); len(qs) != 0 || list.QuestionIndex != 3 {
+		t.Errorf("Unexpected third set %v", qs)
+	}
+	list.QuestionIndex = -5
+	if qs := list.nextSet(2); len(qs) != 0 {
+		t.Errorf("A bad index should not ask anything, got %v", qs)
+	}
+}
@@ -2,10 +2,13 @@ package wordwalls
 
 import (
 	"log"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/domino14/gosports/channels"
+	"github.com/domino14/gosports/leaderboard"
+	"github.com/domino14/gosports/lexicon"
 )
 
 // This file will contain the global maps that represent game states and
@@ -23,12 +26,37 @@ const (
 // gameState represents the state for a single game. This has a lock
 // to protect its inner members.
 type gameState struct {
-	scores         map[string]int
+	scores map[string]int
+	// The words each user solved this round, and when.
+	solved map[string][]string
+	solves []Solve
+	// Each player's guesses that didn't count.
+	guessCounts map[string]GuessCounts
+	// The shared score in collaborative games.
+	teamScore int
+	// Each player's own answer hash in parallel games.
+	playerHashes map[string]map[string]Answer
+	// Set while someone is in study mode.
+	study *studySession
+	// A list that was loaded at the table, to be played instead of the
+	// one in the game options.
+	loadedList *WordList
+	// When the current round started, and when players in parallel
+	// games cleared their walls.
+	startedAt      time.Time
+	finishedAt     map[string]time.Time
 	options        *GameOptions
 	list           *WordList
 	going          gameGoingState
 	countdownTimer *time.Timer
 	gameTimer      *time.Timer
+	// Counts the rounds started at this table, so that timers from a
+	// canceled round can tell they are stale.
+	round int
+	// Where the word list was at before the current round's questions
+	// were pulled, and the questions that were.
+	roundStartIndex int
+	roundQuestions  []Question
 	sync.RWMutex
 }
 
@@ -42,6 +70,14 @@ type gamestatePopulation struct {
 
 var gameStates gamestatePopulation
 
+// Leaderboards is where daily challenge results go. If it is nil, they
+// are not kept.
+var Leaderboards leaderboard.LeaderboardStore
+
+// MissedBingos is where the bingos missed in daily challenges are
+// counted. If it is nil, they are not.
+var MissedBingos leaderboard.MissedBingoStore
+
 func init() {
 	gameStates.reset()
 }
@@ -54,7 +90,7 @@ func (gs *gamestatePopulation) createState(table channels.Realm) *gameState {
 	gs.Lock()
 	defer gs.Unlock()
 
-	log.Println("[DEBUG] In createState: %v", table)
+	log.Printf("[DEBUG] In createState: %v", table)
 	state := &gameState{}
 	// Start in the "Done" state.
 	state.going = GameDone
@@ -117,7 +153,40 @@ func (s *gameState) setOptions(options *GameOptions) {
 func (s *gameState) setList(list *WordList) {
 	s.list = list
 	// Make a new scores map too.
+	s.resetScores()
+}
+
+func (s *gameState) resetScores() {
 	s.scores = make(map[string]int)
+	s.solved = make(map[string][]string)
+	s.solves = nil
+	s.guessCounts = make(map[string]GuessCounts)
+	s.teamScore = 0
+	s.playerHashes = nil
+	s.finishedAt = make(map[string]time.Time)
+}
+
+// setupPlayerWalls gives each player their own copy of the current
+// answer hash, for parallel games.
+func (s *gameState) setupPlayerWalls(players []string) {
+	s.playerHashes = make(map[string]map[string]Answer)
+	for _, player := range players {
+		hash := make(map[string]Answer)
+		for word, answer := range s.list.answerHash {
+			hash[word] = answer
+		}
+		s.playerHashes[player] = hash
+		s.scores[player] = 0
+	}
+}
+
+// challenge returns the daily challenge this table is playing, if it
+// is one of ours. Otherwise the challenge list comes from Webolith.
+func (s *gameState) challenge() *DailyChallenge {
+	if s.options.gameType() != Challenge || s.options.ChallengeId == 0 {
+		return nil
+	}
+	return dailyChallenges.get(s.options.ChallengeId)
 }
 
 func (s *gameState) nextQuestionSet(numQuestions int) []Question {
@@ -126,36 +195,78 @@ func (s *gameState) nextQuestionSet(numQuestions int) []Question {
 	}
 	// Reset the scores. Setting to a new map should hopefully
 	// GC the old one :P
-	s.scores = make(map[string]int)
-	return s.list.nextSet(numQuestions)
+	s.resetScores()
+	s.roundStartIndex = s.list.QuestionIndex
+	s.roundQuestions = s.list.nextSet(numQuestions)
+	return s.roundQuestions
 }
 
-// Check if guess is in answer hash. If it is, increase user score by 1.
+// Check if guess is in answer hash. If it is, increase user score by
+// what the table's scoring rule says the answer is worth. In
+// collaborative games, the team score goes up as well. In
+// parallel games, each player guesses from their own answer hash. If
+// the guess is wrong, the feedback for the guesser is returned instead.
 // XXX: Nil pointer errors in gs.listMap[table] if we restart go server
 // while game is going. We will need to save state between restarts
 // somehow.
 func (gs *gamestatePopulation) guess(data string, table channels.Realm,
-	user string) *CorrectAnswer {
+	user string) (*CorrectAnswer, *GuessFeedback) {
 	state := gs.getState(table)
 	return state.guess(data, user)
 }
 
-func (s *gameState) guess(data string, user string) *CorrectAnswer {
+func (s *gameState) guess(data string, user string) (*CorrectAnswer,
+	*GuessFeedback) {
 	s.Lock()
 	defer s.Unlock()
-	if answer, ok := s.list.answerHash[data]; ok {
+	data = lexicon.Normalize(data)
+	if data == "" || s.study != nil {
+		// There is no wall to guess at while someone is studying.
+		return nil, nil
+	}
+	answerHash := s.list.answerHash
+	if s.options.gameType() == Parallel {
+		// Watchers don't have a wall, so there's nothing to guess.
+		var ok bool
+		if answerHash, ok = s.playerHashes[user]; !ok {
+			return nil, nil
+		}
+	}
+	if answer, ok := answerHash[data]; ok {
 		ca := &CorrectAnswer{}
 		ca.Answer = data
 		ca.Idx = answer.Idx
 		ca.User = user
 		ca.Alphagram = answer.Alphagram
-		delete(s.list.answerHash, data)
+		now := time.Now()
+		ca.Elapsed = elapsedMillis(s.startedAt, now)
+		delete(answerHash, data)
+		ca.Remaining = len(answerHash)
+		if ca.Remaining == 0 && (s.options.gameType() == Parallel ||
+			s.options.gameType() == Challenge) {
+			// The challenge leaderboard breaks ties by time remaining.
+			s.finishedAt[user] = now
+		}
+		ca.Points = s.scorer().Answer(ScoredAnswer{
+			Word:       data,
+			Alphagram:  answer.Alphagram,
+			Elapsed:    now.Sub(s.startedAt),
+			Timer:      time.Duration(s.options.timerSecs()) * time.Second,
+			FirstSolve: s.firstSolve(answer.Alphagram),
+		})
 		// nil value for int is 0 so this will work.
-		s.scores[user] = s.scores[user] + 1
+		s.scores[user] = s.scores[user] + ca.Points
+		s.solved[user] = append(s.solved[user], data)
+		s.solves = append(s.solves, Solve{User: user, Word: data,
+			Alphagram: answer.Alphagram, Elapsed: ca.Elapsed})
 		ca.Score = s.scores[user]
-		return ca
+		if s.options.gameType() == Collaborative {
+			s.teamScore += ca.Points
+			ca.TeamScore = s.teamScore
+		}
+		return ca, nil
 	}
-	return nil
+	return nil, s.missed(data, user)
 }
 
 func (gs *gamestatePopulation) scores(table channels.Realm) map[string]int {
@@ -163,11 +274,193 @@ func (gs *gamestatePopulation) scores(table channels.Realm) map[string]int {
 	return state.scores
 }
 
+// cleared is true if every answer on the wall has been found. In
+// parallel games, every player has to have cleared their own wall.
+func (s *gameState) cleared() bool {
+	if s.options.gameType() == Parallel {
+		for _, hash := range s.playerHashes {
+			if len(hash) > 0 {
+				return false
+			}
+		}
+		return len(s.playerHashes) > 0
+	}
+	return s.list != nil && len(s.list.answerHash) == 0
+}
+
+// timeRemaining is how many seconds the user had left when they were
+// done, given that the round ended at end.
+func (s *gameState) timeRemaining(user string, end time.Time) int {
+	if finished, ok := s.finishedAt[user]; ok {
+		end = finished
+	}
+	remaining := s.options.timerSecs() - int(end.Sub(s.startedAt).Seconds())
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// challengeResults are what a daily challenge round adds to the
+// leaderboard and the missed bingo counts.
+type challengeResults struct {
+	entries []leaderboard.Entry
+	// The day the missed bingos count towards.
+	date string
+}
+
+// challengeResults collects everyone's results for this round, if it
+// was a daily challenge. The state must be locked.
+func (s *gameState) challengeResults(players []string,
+	end time.Time) *challengeResults {
+	if s.options.gameType() != Challenge || s.options.ChallengeId == 0 {
+		return nil
+	}
+	submitters := make(map[string]bool)
+	for _, player := range players {
+		submitters[player] = true
+	}
+	for user := range s.scores {
+		submitters[user] = true
+	}
+	results := &challengeResults{date: challengeDate(end)}
+	if challenge := s.challenge(); challenge != nil {
+		results.date = challenge.Date
+	}
+	for user := range submitters {
+		results.entries = append(results.entries, leaderboard.Entry{
+			ChallengeID:   s.options.ChallengeId,
+			User:          user,
+			Score:         s.scores[user],
+			TimeRemaining: s.timeRemaining(user, end),
+			Submitted:     end,
+		})
+	}
+	return results
+}
+
+// submitChallengeResults puts a challenge round's results on the
+// leaderboard, and counts the bingos each player missed. Only a user's
+// first try at a challenge counts, so replays don't add to the missed
+// bingos either; if there is no leaderboard to tell, every player's
+// misses count. This writes to disk, so it should be called without
+// the state locked.
+func submitChallengeResults(result *RoundResult) {
+	results := result.challenge
+	if results == nil {
+		return
+	}
+	var firstTries map[string]bool
+	if Leaderboards != nil {
+		kept, err := Leaderboards.SubmitAll(results.entries)
+		if err != nil {
+			log.Println("[ERROR] Submitting to leaderboard", err)
+		}
+		firstTries = make(map[string]bool)
+		for _, e := range kept {
+			firstTries[e.User] = true
+		}
+	}
+	if MissedBingos == nil {
+		return
+	}
+	alphagrams := []string{}
+	for player, missed := range result.Missed {
+		if firstTries == nil || firstTries[player] {
+			alphagrams = append(alphagrams, missed...)
+		}
+	}
+	if len(alphagrams) == 0 {
+		return
+	}
+	err := MissedBingos.RecordMissed(results.date, result.Lexicon,
+		alphagrams)
+	if err != nil {
+		log.Println("[ERROR] Recording missed bingos", err)
+	}
+}
+
+// missedAlphagrams returns the alphagrams in this answer hash, i.e. the
+// ones that still had answers left to find.
+func missedAlphagrams(answerHash map[string]Answer) []string {
+	seen := make(map[string]bool)
+	missed := []string{}
+	for _, answer := range answerHash {
+		if !seen[answer.Alphagram] {
+			seen[answer.Alphagram] = true
+			missed = append(missed, answer.Alphagram)
+		}
+	}
+	sort.Strings(missed)
+	return missed
+}
+
+// standings ranks the players by score.
+func (s *gameState) standings() []Standing {
+	standings := []Standing{}
+	for user, score := range s.scores {
+		standings = append(standings, Standing{User: user, Score: score})
+	}
+	sort.Sort(byScore(standings))
+	for i := range standings {
+		if i > 0 && standings[i].Score == standings[i-1].Score {
+			standings[i].Rank = standings[i-1].Rank
+		} else {
+			standings[i].Rank = i + 1
+		}
+	}
+	return standings
+}
+
+// summary sums up the round that was just played, which ended at end.
+func (s *gameState) summary(end time.Time) *RoundSummary {
+	finished := make(map[string]int64)
+	for user, at := range s.finishedAt {
+		finished[user] = elapsedMillis(s.startedAt, at)
+	}
+	roundMillis := elapsedMillis(s.startedAt, end)
+	summary := &RoundSummary{
+		GameType:  s.options.gameType(),
+		Scores:    s.scores,
+		Solved:    s.solved,
+		Standings: s.standings(),
+		Cleared:   s.cleared(),
+		Fastest:   fastestSolver(s.solves, roundMillis, finished),
+		Guesses:   s.guessCounts,
+		Review:    s.roundQuestions,
+	}
+	if summary.GameType == Collaborative {
+		summary.TeamScore = s.teamScore
+	}
+	return summary
+}
+
 func (gs *gamestatePopulation) timer(table channels.Realm) int {
 	state := gs.getState(table)
 	state.RLock()
 	defer state.RUnlock()
-	return state.options.TimerSecs
+	return state.options.timerSecs()
+}
+
+func (gs *gamestatePopulation) gameType(table channels.Realm) GameType {
+	state := gs.getState(table)
+	state.RLock()
+	defer state.RUnlock()
+	return state.options.gameType()
+}
+
+// gameTypeIfExists is gameType, for tables that might already be gone.
+func (gs *gamestatePopulation) gameTypeIfExists(table channels.Realm) GameType {
+	state := gs.getState(table)
+	if state == nil {
+		return ""
+	}
+	state.RLock()
+	defer state.RUnlock()
+	if state.options == nil {
+		return ""
+	}
+	return state.options.gameType()
 }
 
 func (gs *gamestatePopulation) getGameGoing(table channels.Realm) gameGoingState {
@@ -197,3 +490,13 @@ func (s *gameState) cancelTimers() {
 		log.Printf("[DEBUG] Canceling countdownTimer: %v", cl2)
 	}
 }
+
+// Cancel the countdown, and put the questions that were about to be
+// asked back into the list.
+func (s *gameState) cancelCountdown() {
+	s.cancelTimers()
+	if s.list != nil {
+		s.list.QuestionIndex = s.roundStartIndex
+	}
+	s.going = GameDone
+}
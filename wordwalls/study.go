@@ -0,0 +1,229 @@
+package wordwalls
+
+// This file contains study mode, a single-player mode with no timer.
+// The player goes through a word list one alphagram at a time, reveals
+// the answers when they want to, and marks each alphagram as known or
+// unknown. Study lists are loaded locally, so there is no round-trip to
+// Webolith.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/domino14/gosports/channels"
+)
+
+const (
+	FailureNotStudying = "NOT_STUDYING"
+	FailureBadWordList = "BAD_WORD_LIST"
+)
+
+const (
+	StudyMT     channels.MessageType = "study"
+	StudyDoneMT channels.MessageType = "studyDone"
+)
+
+// LocalListLoader loads a word list without going to Webolith.
+type LocalListLoader interface {
+	LoadList(name string) (*WordList, error)
+}
+
+// ListDir loads word lists from JSON files in the directory given by
+// the STUDY_LIST_DIR environment variable. The files are in the same
+// format as Webolith's word list API.
+type ListDir struct{}
+
+func (l ListDir) LoadList(name string) (*WordList, error) {
+	dir := os.Getenv("STUDY_LIST_DIR")
+	if dir == "" {
+		log.Println("[ERROR] No study list directory")
+		return nil, fmt.Errorf("no study list directory.")
+	}
+	if name == "" || strings.ContainsAny(name, `/\.`) {
+		return nil, fmt.Errorf("bad list name: %v", name)
+	}
+	body, err := ioutil.ReadFile(filepath.Join(dir, name+".json"))
+	if err != nil {
+		return nil, err
+	}
+	list := &WordList{}
+	err = json.Unmarshal(body, list)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// StudyLists is where study mode gets its lists from.
+var StudyLists LocalListLoader = ListDir{}
+
+// studySession is the state of a study session at a table.
+type studySession struct {
+	// The only user who can drive this session.
+	user     string
+	known    int
+	unknown  int
+	revealed bool
+}
+
+// StudyCard is a single alphagram being studied. The answers are only
+// sent once revealed.
+type StudyCard struct {
+	Position  int      `json:"position"`
+	Total     int      `json:"total"`
+	Alphagram string   `json:"alphagram"`
+	Answers   []string `json:"answers,omitempty"`
+}
+
+// StudySummary is sent when the player is done going through the list.
+type StudySummary struct {
+	Known   int   `json:"known"`
+	Unknown int   `json:"unknown"`
+	Missed  []int `json:"missed"`
+}
+
+// handle a study command:
+//
+//	study <list>
+//	reveal
+//	known
+//	unknown
+func handleStudyCommand(fields []string, table channels.Realm, user string,
+	connId string, sender channels.SocketMessageSender) {
+	sendFail := func(errorCode string) {
+		sender.BroadcastMessage(table, FailMT, errorCode)
+	}
+	if users.seatConn(table, user) != connId {
+		sendFail(FailureNotSeatOwner)
+		return
+	}
+	st := gameStates.getState(table)
+	st.Lock()
+	defer st.Unlock()
+
+	if fields[0] == "study" {
+		if len(fields) != 2 {
+			sendFail(FailureBadCommand)
+			return
+		}
+		if st.going != GameDone {
+			sendFail(FailureGameGoing)
+			return
+		}
+		if state, _ := users.userState(table, user); !state.isSeated() {
+			sendFail(FailureNotSeated)
+			return
+		}
+		// Studying takes over the table, so only the host gets to do
+		// it when other players are sitting there too.
+		if users.host(table) != user && !users.soleSeated(table, user) {
+			sendFail(FailureNotHost)
+			return
+		}
+		list, err := StudyLists.LoadList(fields[1])
+		if err != nil {
+			log.Println("[ERROR] Loading study list", err)
+			sendFail(FailureNullWordList)
+			return
+		}
+		if err := list.CheckIndices(); err != nil {
+			log.Println("[ERROR] Study list", fields[1], err)
+			sendFail(FailureBadWordList)
+			return
+		}
+		st.setList(list)
+		st.study = &studySession{user: user}
+		st.going = GameStarted
+		st.sendStudyCard(table, sender)
+		return
+	}
+
+	if st.study == nil || st.study.user != user {
+		sendFail(FailureNotStudying)
+		return
+	}
+	switch fields[0] {
+	case "reveal":
+		st.study.revealed = true
+		st.sendStudyCard(table, sender)
+	case "known", "unknown":
+		if fields[0] == "known" {
+			st.study.known++
+		} else {
+			st.study.unknown++
+			st.list.markMissed(st.list.CurQuestions[st.list.QuestionIndex])
+		}
+		st.list.QuestionIndex++
+		st.study.revealed = false
+		if st.list.QuestionIndex < len(st.list.CurQuestions) {
+			st.sendStudyCard(table, sender)
+			return
+		}
+		st.finishStudy(table, sender)
+	}
+}
+
+// Send the alphagram being studied. The state must be locked.
+func (s *gameState) sendStudyCard(table channels.Realm,
+	sender channels.SocketMessageSender) {
+	if s.list.QuestionIndex >= len(s.list.CurQuestions) {
+		s.finishStudy(table, sender)
+		return
+	}
+	q := s.list.OrigQuestions[s.list.CurQuestions[s.list.QuestionIndex]]
+	card := StudyCard{
+		Position:  s.list.QuestionIndex + 1,
+		Total:     len(s.list.CurQuestions),
+		Alphagram: q.Question,
+	}
+	if s.study.revealed {
+		card.Answers = q.Answers
+	}
+	msg, err := json.Marshal(card)
+	if err != nil {
+		log.Println("[ERROR] Marshalling study card", err)
+		return
+	}
+	// Only the studier gets to see the answers.
+	sender.SendMessage(table, StudyMT, string(msg), s.study.user)
+}
+
+// Wrap up the study session. The state must be locked.
+func (s *gameState) finishStudy(table channels.Realm,
+	sender channels.SocketMessageSender) {
+	s.list.GoneThruOnce = true
+	msg, err := json.Marshal(StudySummary{
+		Known:   s.study.known,
+		Unknown: s.study.unknown,
+		Missed:  s.list.Missed,
+	})
+	if err != nil {
+		log.Println("[ERROR] Marshalling study summary", err)
+	}
+	user := s.study.user
+	s.study = nil
+	s.going = GameDone
+	sender.SendMessage(table, StudyDoneMT, string(msg), user)
+}
+
+// endStudy stops the user's study session at the table, if they have
+// one, so that the table isn't stuck once they leave.
+func endStudy(table channels.Realm, user string) {
+	st := gameStates.getState(table)
+	if st == nil {
+		return
+	}
+	st.Lock()
+	defer st.Unlock()
+	if st.study == nil || st.study.user != user {
+		return
+	}
+	log.Printf("[DEBUG] %s left table %s while studying", user, table)
+	st.study = nil
+	st.going = GameDone
+}
@@ -0,0 +1,163 @@
+package wordwalls
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/domino14/gosports/channels"
+)
+
+func TestStudySession(t *testing.T) {
+	os.Setenv("STUDY_LIST_DIR", "test_files")
+	defer os.Unsetenv("STUDY_LIST_DIR")
+	realm := toRealm(tablenum)
+	sender := newTable(realm)
+	MessageHandler.RealmJoin(realm, "cesar", "id1", true)
+	MessageHandler.RealmJoin(realm, "messi", "id2", false)
+	tableCommand("study ../get_list_response", "cesar", realm)
+	tableCommand("study get_list_response", "cesar", realm)
+	if gameStates.getGameGoing(realm) != GameStarted {
+		t.Fatalf("Study session should have started")
+	}
+	// Only the player who started studying can drive the session.
+	tableCommand("known", "messi", realm)
+	tableCommand("reveal", "cesar", realm)
+	cards := sender.messages(StudyMT)
+	if len(cards) != 2 {
+		t.Fatalf("Unexpected study cards: %v", cards)
+	}
+	if cards[0] != `{"position":1,"total":50,"alphagram":"BEEQSSTU"}` ||
+		cards[1] != `{"position":1,"total":50,"alphagram":"BEEQSSTU",`+
+			`"answers":["BEQUESTS"]}` {
+		t.Errorf("Unexpected study cards: %v", cards)
+	}
+	for _, to := range sender.recipients(StudyMT) {
+		if to != "cesar" {
+			t.Errorf("Study cards should only go to cesar, not %q", to)
+		}
+	}
+	tableCommand("unknown", "cesar", realm)
+	for i := 1; i < 50; i++ {
+		tableCommand("known", "cesar", realm)
+	}
+	if gameStates.getGameGoing(realm) != GameDone {
+		t.Fatalf("Study session should have ended")
+	}
+	done := sender.messages(StudyDoneMT)
+	if to := sender.recipients(StudyDoneMT); len(to) != 1 || to[0] != "cesar" {
+		t.Errorf("Study summary should only go to cesar, not %v", to)
+	}
+	if len(done) != 1 {
+		t.Fatalf("Should have sent one study summary, sent %v", done)
+	}
+	summary := &StudySummary{}
+	if err := json.Unmarshal([]byte(done[0]), summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.Known != 49 || summary.Unknown != 1 ||
+		len(summary.Missed) != 1 || summary.Missed[0] != 0 {
+		t.Errorf("Unexpected study summary: %v", summary)
+	}
+	list := gameStates.getState(realm).list
+	if list.NumMissed != 1 || list.NumFirstMissed != 1 || !list.GoneThruOnce {
+		t.Errorf("Missed questions were not tracked: %v %v", list.Missed,
+			list.FirstMissed)
+	}
+	fails := sender.messages(FailMT)
+	if len(fails) != 2 || fails[0] != FailureNullWordList ||
+		fails[1] != FailureNotStudying {
+		t.Errorf("Unexpected failures: %v", fails)
+	}
+}
+
+func TestLeavingEndsStudySession(t *testing.T) {
+	os.Setenv("STUDY_LIST_DIR", "test_files")
+	defer os.Unsetenv("STUDY_LIST_DIR")
+	realm := toRealm(tablenum)
+	newTable(realm)
+	MessageHandler.RealmJoin(realm, "cesar", "id1", true)
+	MessageHandler.RealmJoin(realm, "messi", "id2", false)
+	tableCommand("study get_list_response", "cesar", realm)
+	if gameStates.getGameGoing(realm) != GameStarted {
+		t.Fatalf("Study session should have started")
+	}
+	// Someone else leaving doesn't matter.
+	MessageHandler.RealmLeave(realm, "messi", "id2")
+	time.Sleep(50 * time.Millisecond)
+	if gameStates.getGameGoing(realm) != GameStarted {
+		t.Fatalf("Study session should still be going")
+	}
+	MessageHandler.RealmLeave(realm, "cesar", "id1")
+	for i := 0; i < 100; i++ {
+		if gameStates.getGameGoing(realm) == GameDone {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("Study session should have ended when cesar left")
+}
+
+func TestOnlyHostStudiesWithOthersSeated(t *testing.T) {
+	os.Setenv("STUDY_LIST_DIR", "test_files")
+	defer os.Unsetenv("STUDY_LIST_DIR")
+	realm := toRealm(tablenum)
+	sender := newTable(realm)
+	MessageHandler.RealmJoin(realm, "cesar", "id1", true)
+	MessageHandler.RealmJoin(realm, "messi", "id2", true)
+	tableCommand("study get_list_response", "messi", realm)
+	if gameStates.getGameGoing(realm) != GameDone {
+		t.Fatalf("messi should not have been able to study")
+	}
+	fails := sender.messages(FailMT)
+	if len(fails) != 1 || fails[0] != FailureNotHost {
+		t.Errorf("Unexpected failures: %v", fails)
+	}
+	// Once the host is watching, messi is the only one sitting.
+	tableCommand("watch", "cesar", realm)
+	tableCommand("study get_list_response", "messi", realm)
+	if gameStates.getGameGoing(realm) != GameStarted {
+		t.Errorf("messi should have been able to study alone")
+	}
+}
+
+func TestStudyListWithBadIndices(t *testing.T) {
+	os.Setenv("STUDY_LIST_DIR", "test_files")
+	defer os.Unsetenv("STUDY_LIST_DIR")
+	realm := toRealm(tablenum)
+	sender := newTable(realm)
+	MessageHandler.RealmJoin(realm, "cesar", "id1", true)
+	tableCommand("study truncated_list", "cesar", realm)
+	if gameStates.getGameGoing(realm) != GameDone {
+		t.Errorf("Should not have studied a list with bad indices")
+	}
+	fails := sender.messages(FailMT)
+	if len(fails) != 1 || fails[0] != FailureBadWordList {
+		t.Errorf("Unexpected failures: %v", fails)
+	}
+}
+
+func TestGuessesIgnoredWhileStudying(t *testing.T) {
+	os.Setenv("STUDY_LIST_DIR", "test_files")
+	defer os.Unsetenv("STUDY_LIST_DIR")
+	realm := toRealm(tablenum)
+	sender := newTable(realm)
+	// Study mode doesn't need any game options.
+	gameStates.getState(realm).setOptions(nil)
+	MessageHandler.RealmJoin(realm, "cesar", "id1", true)
+	tableCommand("study get_list_response", "cesar", realm)
+	if gameStates.getGameGoing(realm) != GameStarted {
+		t.Fatalf("Study session should have started")
+	}
+	guess := channels.Message{Data: "BEQUESTS",
+		Mtype: channels.MessageType(GuessMT), From: "cesar"}
+	guess.SetRealm(realm)
+	MessageHandler.HandleMessage(guess)
+	if scores := sender.messages(ScoreMT); len(scores) != 0 {
+		t.Errorf("Guesses should not count while studying, got %v", scores)
+	}
+	if gameStates.getGameGoing(realm) != GameStarted {
+		t.Errorf("Study session should still be going")
+	}
+}
@@ -0,0 +1,80 @@
+package wordwalls
+
+// This file keeps track of when each answer was found, so we can tell
+// who solved fastest.
+
+import (
+	"sort"
+	"time"
+)
+
+// Solve is a single correct answer, and how long into the round it was
+// found.
+type Solve struct {
+	User      string `json:"user"`
+	Word      string `json:"word"`
+	Alphagram string `json:"alphagram"`
+	// Milliseconds since the round started.
+	Elapsed int64 `json:"elapsed"`
+}
+
+// FastestSolver is the player who found answers at the quickest rate
+// over the time they were playing: the whole round, or until they
+// cleared their wall.
+type FastestSolver struct {
+	User      string  `json:"user"`
+	Solved    int     `json:"solved"`
+	PerMinute float64 `json:"perMinute"`
+}
+
+type bySpeed []FastestSolver
+
+func (s bySpeed) Len() int      { return len(s) }
+func (s bySpeed) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s bySpeed) Less(i, j int) bool {
+	if s[i].PerMinute != s[j].PerMinute {
+		return s[i].PerMinute > s[j].PerMinute
+	}
+	if s[i].Solved != s[j].Solved {
+		return s[i].Solved > s[j].Solved
+	}
+	return s[i].User < s[j].User
+}
+
+func elapsedMillis(since time.Time, now time.Time) int64 {
+	return int64(now.Sub(since) / time.Millisecond)
+}
+
+// fastestSolver returns the fastest solver in the round, or nil if no
+// one solved anything. Everyone's rate is over the whole round, which
+// lasted roundMillis, except for players who cleared their wall early;
+// finished has how far into the round they did that.
+func fastestSolver(solves []Solve, roundMillis int64,
+	finished map[string]int64) *FastestSolver {
+	counts := make(map[string]int)
+	for _, solve := range solves {
+		counts[solve.User]++
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+	solvers := []FastestSolver{}
+	for user, count := range counts {
+		millis := roundMillis
+		if f, ok := finished[user]; ok {
+			millis = f
+		}
+		// Count at least a second, so a wall cleared in no time
+		// doesn't make for an absurd rate.
+		if millis < 1000 {
+			millis = 1000
+		}
+		solvers = append(solvers, FastestSolver{
+			User:      user,
+			Solved:    count,
+			PerMinute: float64(count) * 60000 / float64(millis),
+		})
+	}
+	sort.Sort(bySpeed(solvers))
+	return &solvers[0]
+}
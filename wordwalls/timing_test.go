@@ -0,0 +1,39 @@
+package wordwalls
+
+import (
+	"testing"
+)
+
+func TestFastestSolver(t *testing.T) {
+	if fastestSolver(nil, 180000, nil) != nil {
+		t.Errorf("No one solved anything")
+	}
+	solves := []Solve{{User: "xavi", Word: "TRAINED", Elapsed: 100}}
+	for i := 0; i < 50; i++ {
+		solves = append(solves, Solve{User: "messi", Word: "QI",
+			Elapsed: int64(i+1) * 3000})
+	}
+	// One quick answer doesn't beat fifty over the round.
+	fastest := fastestSolver(solves, 180000, nil)
+	if fastest == nil || fastest.User != "messi" || fastest.Solved != 50 ||
+		fastest.PerMinute != 50.0/3 {
+		t.Errorf("Unexpected fastest solver %v", fastest)
+	}
+	fastest = fastestSolver([]Solve{
+		{User: "cesar", Word: "QI", Elapsed: 2000},
+		{User: "messi", Word: "ZA", Elapsed: 3000},
+		{User: "cesar", Word: "RETAINS", Elapsed: 12000},
+		{User: "messi", Word: "STAINER", Elapsed: 6000},
+	}, 60000, map[string]int64{"messi": 6000})
+	// messi cleared their wall six seconds in.
+	if fastest.User != "messi" || fastest.Solved != 2 ||
+		fastest.PerMinute != 20 {
+		t.Errorf("Unexpected fastest solver %v", fastest)
+	}
+	// A wall cleared in no time counts as taking a second.
+	fastest = fastestSolver([]Solve{{User: "xavi", Word: "QI", Elapsed: 10}},
+		60000, map[string]int64{"xavi": 10})
+	if fastest.PerMinute != 60 {
+		t.Errorf("Unexpected fastest solver %v", fastest)
+	}
+}
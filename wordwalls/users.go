@@ -1,7 +1,10 @@
 package wordwalls
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"sort"
 	"sync"
 
 	"github.com/domino14/gosports/channels"
@@ -12,6 +15,36 @@ type UserState int
 type UserInfo struct {
 	connIds map[string]bool
 	state   UserState
+	// The connection that owns this user's seat. Only this connection
+	// may change the user's state.
+	seatConn string
+}
+
+// TabPolicy decides what happens when a user opens a second connection
+// (i.e. another browser tab) to a table they are already in.
+type TabPolicy int
+
+const (
+	// The newest connection takes over the seat, and older connections
+	// are kicked.
+	TabTakeover TabPolicy = iota
+	// Several connections are allowed. The seat stays with the
+	// connection that owns it; the rest can only watch.
+	TabMultiple
+)
+
+// MultiTabPolicy is the policy in effect for all tables.
+var MultiTabPolicy = TabTakeover
+
+// ParseTabPolicy parses a tab policy given as "takeover" or "allow".
+func ParseTabPolicy(policy string) (TabPolicy, error) {
+	switch policy {
+	case "takeover":
+		return TabTakeover, nil
+	case "allow":
+		return TabMultiple, nil
+	}
+	return TabTakeover, fmt.Errorf("unknown tab policy: %v", policy)
 }
 
 const (
@@ -26,6 +59,10 @@ const (
 type userPopulation struct {
 	sync.RWMutex
 	userMap map[channels.Realm]map[string]*UserInfo
+	tables  map[channels.Realm]*tableInfo
+	// Connections that were turned away when they joined. They never
+	// made it to a table, so there's nothing to do when they leave.
+	rejected map[string]bool
 }
 
 func (u UserState) String() string {
@@ -42,6 +79,11 @@ func (u UserState) String() string {
 
 var users userPopulation
 
+var (
+	errTableFull = errors.New("all seats at this table are taken")
+	errNotSeated = errors.New("user is not sitting")
+)
+
 func init() {
 	users.reset()
 }
@@ -52,10 +94,16 @@ func toRealm(tbl string) channels.Realm {
 
 func (u *userPopulation) reset() {
 	users.userMap = make(map[channels.Realm]map[string]*UserInfo)
+	users.tables = make(map[channels.Realm]*tableInfo)
+	users.rejected = make(map[string]bool)
 }
 
+// Add a connection for this user. The state is only used if the user
+// is new to this table; otherwise they keep the state of their seat.
+// Returns the ids of any older connections that must be kicked as per
+// MultiTabPolicy.
 func (u *userPopulation) add(table channels.Realm, username string,
-	state UserState, connId string) {
+	state UserState, connId string) []string {
 	log.Printf("Adding user %s to table %s in state %v, connId %s\n", username,
 		table, state, connId)
 	u.Lock()
@@ -70,9 +118,19 @@ func (u *userPopulation) add(table channels.Realm, username string,
 		uInfo = &UserInfo{}
 		usersHere[username] = uInfo
 		uInfo.connIds = make(map[string]bool)
+		uInfo.state = state
+		uInfo.seatConn = connId
+	}
+	var kicked []string
+	if MultiTabPolicy == TabTakeover {
+		for oldId := range uInfo.connIds {
+			kicked = append(kicked, oldId)
+			delete(uInfo.connIds, oldId)
+		}
+		uInfo.seatConn = connId
 	}
 	uInfo.connIds[connId] = true
-	uInfo.state = state
+	return kicked
 }
 
 func (u *userPopulation) remove(table channels.Realm, username string,
@@ -88,6 +146,15 @@ func (u *userPopulation) remove(table channels.Realm, username string,
 			delete(uInfo.connIds, connId)
 			if len(uInfo.connIds) == 0 {
 				delete(usersHere, username)
+				if len(usersHere) == 0 {
+					delete(u.tables, table)
+				}
+			} else if uInfo.seatConn == connId {
+				// Hand the seat over to one of the remaining connections.
+				for otherId := range uInfo.connIds {
+					uInfo.seatConn = otherId
+					break
+				}
 			}
 		}
 	}
@@ -121,30 +188,237 @@ func (u *userPopulation) allowStart(table channels.Realm) bool {
 	return allow
 }
 
-func (u *userPopulation) wantsToPlay(table channels.Realm, username string) {
+func (u *userPopulation) wantsToPlay(table channels.Realm, username string,
+	connId string) error {
 	log.Printf("[DEBUG] User %s wants to play on table %s\n", username, table)
-	u.modifyState(table, username, stWantsToPlay)
+	return u.modifyState(table, username, connId, stWantsToPlay)
 }
 
-func (u *userPopulation) watching(table channels.Realm, username string) {
-	log.Printf("[DEBUG] User %s is watching on table %s\n", username, table)
-	u.modifyState(table, username, stWatching)
+// ready marks a seated user as ready to play.
+func (u *userPopulation) ready(table channels.Realm, username string,
+	connId string) error {
+	return u.setReady(table, username, connId, true)
 }
 
-func (u *userPopulation) sitting(table channels.Realm, username string) {
-	log.Printf("[DEBUG] User %s is sitting on table %s\n", username, table)
-	u.modifyState(table, username, stSitting)
+// unready marks a seated user as no longer ready.
+func (u *userPopulation) unready(table channels.Realm, username string,
+	connId string) error {
+	return u.setReady(table, username, connId, false)
 }
 
-func (u *userPopulation) modifyState(table channels.Realm, username string,
-	state UserState) {
+func (u *userPopulation) setReady(table channels.Realm, username string,
+	connId string, ready bool) error {
 	u.Lock()
 	defer u.Unlock()
-	usersHere := u.userMap[table]
-	if uInfo, ok := usersHere[username]; ok {
-		uInfo.state = state
+	uInfo, err := u.ownedUser(table, username, connId)
+	if err != nil {
+		return err
+	}
+	if !uInfo.state.isSeated() {
+		return errNotSeated
+	}
+	log.Printf("[DEBUG] User %s on table %s ready: %v\n", username, table,
+		ready)
+	if ready {
+		uInfo.state = stWantsToPlay
 	} else {
+		uInfo.state = stSitting
+	}
+	return nil
+}
+
+// players returns the users who are ready to play, sorted.
+func (u *userPopulation) players(table channels.Realm) []string {
+	u.RLock()
+	defer u.RUnlock()
+	players := []string{}
+	for username, uInfo := range u.userMap[table] {
+		if uInfo.state == stWantsToPlay {
+			players = append(players, username)
+		}
+	}
+	sort.Strings(players)
+	return players
+}
+
+// resetReady puts everyone who was ready back to sitting. Returns the
+// users whose state changed.
+func (u *userPopulation) resetReady(table channels.Realm) []string {
+	u.Lock()
+	defer u.Unlock()
+	changed := []string{}
+	for username, uInfo := range u.userMap[table] {
+		if uInfo.state == stWantsToPlay {
+			uInfo.state = stSitting
+			changed = append(changed, username)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func (u *userPopulation) watching(table channels.Realm, username string,
+	connId string) error {
+	log.Printf("[DEBUG] User %s is watching on table %s\n", username, table)
+	return u.modifyState(table, username, connId, stWatching)
+}
+
+func (u *userPopulation) sitting(table channels.Realm, username string,
+	connId string) error {
+	log.Printf("[DEBUG] User %s is sitting on table %s\n", username, table)
+	return u.modifyState(table, username, connId, stSitting)
+}
+
+// userState returns the state of the user at this table, and whether
+// they are at the table at all.
+func (u *userPopulation) userState(table channels.Realm,
+	username string) (UserState, bool) {
+	u.RLock()
+	defer u.RUnlock()
+	if uInfo, ok := u.userMap[table][username]; ok {
+		return uInfo.state, true
+	}
+	return stWatching, false
+}
+
+// presence returns a presence event for this user. A user who is no
+// longer at the table is reported with zero connections.
+func (u *userPopulation) presence(table channels.Realm, username string,
+	event string) Presence {
+	u.RLock()
+	defer u.RUnlock()
+	p := Presence{Event: event, User: username, State: stWatching.String()}
+	if uInfo, ok := u.userMap[table][username]; ok {
+		p.State = uInfo.state.String()
+		p.Conns = len(uInfo.connIds)
+	}
+	return p
+}
+
+// roster returns the presence of everyone at this table, sorted by
+// username.
+func (u *userPopulation) roster(table channels.Realm) []Presence {
+	u.RLock()
+	defer u.RUnlock()
+	roster := []Presence{}
+	host := ""
+	if tInfo, ok := u.tables[table]; ok {
+		host = tInfo.host
+	}
+	for username, uInfo := range u.userMap[table] {
+		roster = append(roster, Presence{
+			User:  username,
+			State: uInfo.state.String(),
+			Conns: len(uInfo.connIds),
+			Host:  host == username,
+		})
+	}
+	sort.Sort(byUser(roster))
+	return roster
+}
+
+// seatConn returns the id of the connection that owns the user's seat.
+func (u *userPopulation) seatConn(table channels.Realm, username string) string {
+	u.RLock()
+	defer u.RUnlock()
+	if uInfo, ok := u.userMap[table][username]; ok {
+		return uInfo.seatConn
+	}
+	return ""
+}
+
+// ownedUser returns the info for a user, if the given connection owns
+// their seat. The caller must hold the lock.
+func (u *userPopulation) ownedUser(table channels.Realm, username string,
+	connId string) (*UserInfo, error) {
+	usersHere := u.userMap[table]
+	uInfo, ok := usersHere[username]
+	if !ok {
 		log.Printf("[ERROR] User %s not in table %s (%v)\n", username, table,
 			usersHere)
+		return nil, fmt.Errorf("user %v not in table %v", username, table)
+	}
+	if uInfo.seatConn != connId {
+		log.Printf("[DEBUG] Connection %s does not own the seat of %s\n",
+			connId, username)
+		return nil, fmt.Errorf("connection %v does not own this seat", connId)
+	}
+	return uInfo, nil
+}
+
+// Modify the state of a user. Only the connection that owns the seat
+// is allowed to do this.
+func (u *userPopulation) modifyState(table channels.Realm, username string,
+	connId string, state UserState) error {
+	u.Lock()
+	defer u.Unlock()
+	uInfo, err := u.ownedUser(table, username, connId)
+	if err != nil {
+		return err
+	}
+	uInfo.state = state
+	return nil
+}
+
+// isSeated is true for users who are taking up a seat at the table.
+func (u UserState) isSeated() bool {
+	return u == stSitting || u == stWantsToPlay
+}
+
+// soleSeated is true if the user is the only one taking up a seat at
+// the table.
+func (u *userPopulation) soleSeated(table channels.Realm,
+	username string) bool {
+	u.RLock()
+	defer u.RUnlock()
+	for other, uInfo := range u.userMap[table] {
+		if uInfo.state.isSeated() != (other == username) {
+			return false
+		}
+	}
+	return true
+}
+
+// sit takes a seat, as long as fewer than maxSeats are taken. Sitting
+// down when already seated does nothing.
+func (u *userPopulation) sit(table channels.Realm, username string,
+	connId string, maxSeats int) error {
+	u.Lock()
+	defer u.Unlock()
+	uInfo, err := u.ownedUser(table, username, connId)
+	if err != nil {
+		return err
+	}
+	if uInfo.state.isSeated() {
+		return nil
+	}
+	numSeated := 0
+	for _, other := range u.userMap[table] {
+		if other.state.isSeated() {
+			numSeated++
+		}
+	}
+	if numSeated >= maxSeats {
+		return errTableFull
+	}
+	log.Printf("[DEBUG] User %s is sitting on table %s\n", username, table)
+	uInfo.state = stSitting
+	return nil
+}
+
+// stand gives up a seat to go watch the game.
+func (u *userPopulation) stand(table channels.Realm, username string,
+	connId string) error {
+	u.Lock()
+	defer u.Unlock()
+	uInfo, err := u.ownedUser(table, username, connId)
+	if err != nil {
+		return err
+	}
+	if !uInfo.state.isSeated() {
+		return errNotSeated
 	}
+	log.Printf("[DEBUG] User %s stood up on table %s\n", username, table)
+	uInfo.state = stWatching
+	return nil
 }
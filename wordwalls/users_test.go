@@ -1,7 +1,9 @@
 package wordwalls
 
 import (
+	"strings"
 	"testing"
+	"time"
 )
 
 const (
@@ -12,6 +14,8 @@ const (
 func TestJoin(t *testing.T) {
 	gameStates.reset()
 	users.reset()
+	MultiTabPolicy = TabMultiple
+	defer func() { MultiTabPolicy = TabTakeover }()
 	realm := toRealm(tableName)
 	users.add(realm, username, stSitting, "id1")
 	users.add(realm, username, stWatching, "id2")
@@ -23,6 +27,8 @@ func TestJoin(t *testing.T) {
 func TestJoinAndLeave(t *testing.T) {
 	gameStates.reset()
 	users.reset()
+	MultiTabPolicy = TabMultiple
+	defer func() { MultiTabPolicy = TabTakeover }()
 	realm := toRealm(tableName)
 	users.add(realm, username, stSitting, "id1")
 	users.add(realm, username, stWatching, "id2")
@@ -58,11 +64,13 @@ func TestSingleJoinAndLeaveMismatchedIds(t *testing.T) {
 func TestJoinLeaveAndStart(t *testing.T) {
 	gameStates.reset()
 	users.reset()
+	MultiTabPolicy = TabMultiple
+	defer func() { MultiTabPolicy = TabTakeover }()
 	realm := toRealm(tableName)
 	users.add(realm, username, stSitting, "id1")
 	users.add(realm, username, stWatching, "id2")
 	users.remove(realm, username, "id2")
-	users.wantsToPlay(realm, username)
+	users.wantsToPlay(realm, username, "id1")
 	if len(users.userMap[realm]) != 1 {
 		t.Errorf("Length should have been 1.")
 	}
@@ -70,3 +78,136 @@ func TestJoinLeaveAndStart(t *testing.T) {
 		t.Errorf("Should have been allowed to start")
 	}
 }
+
+func TestTakeoverKicksOlderConnection(t *testing.T) {
+	gameStates.reset()
+	users.reset()
+	realm := toRealm(tableName)
+	users.add(realm, username, stSitting, "id1")
+	kicked := users.add(realm, username, stWatching, "id2")
+	if len(kicked) != 1 || kicked[0] != "id1" {
+		t.Errorf("Should have kicked id1, kicked %v", kicked)
+	}
+	if users.seatConn(realm, username) != "id2" {
+		t.Errorf("Seat should belong to id2")
+	}
+	if users.userMap[realm][username].state != stSitting {
+		t.Errorf("New connection should have kept the seat")
+	}
+	// The kicked connection leaving should not remove the user.
+	users.remove(realm, username, "id1")
+	if len(users.userMap[realm]) != 1 {
+		t.Errorf("Length should have been 1.")
+	}
+}
+
+func TestOnlySeatOwnerChangesState(t *testing.T) {
+	gameStates.reset()
+	users.reset()
+	MultiTabPolicy = TabMultiple
+	defer func() { MultiTabPolicy = TabTakeover }()
+	realm := toRealm(tableName)
+	users.add(realm, username, stSitting, "id1")
+	kicked := users.add(realm, username, stWatching, "id2")
+	if len(kicked) != 0 {
+		t.Errorf("Should not have kicked anyone, kicked %v", kicked)
+	}
+	if users.wantsToPlay(realm, username, "id2") == nil {
+		t.Errorf("id2 should not have been able to change state")
+	}
+	if users.allowStart(realm) {
+		t.Errorf("Should not have been allowed to start")
+	}
+	// Once the owner leaves, the seat passes on.
+	users.remove(realm, username, "id1")
+	if users.wantsToPlay(realm, username, "id2") != nil {
+		t.Errorf("id2 should now own the seat")
+	}
+	if !users.allowStart(realm) {
+		t.Errorf("Should have been allowed to start")
+	}
+}
+
+func TestSitMaxSeats(t *testing.T) {
+	gameStates.reset()
+	users.reset()
+	realm := toRealm(tableName)
+	users.add(realm, "cesar", stSitting, "id1")
+	users.add(realm, "messi", stWatching, "id2")
+	users.add(realm, "xavi", stWatching, "id3")
+	if err := users.sit(realm, "messi", "id2", 2); err != nil {
+		t.Errorf("messi should have been able to sit: %v", err)
+	}
+	if err := users.sit(realm, "xavi", "id3", 2); err != errTableFull {
+		t.Errorf("Table should have been full, got %v", err)
+	}
+	if err := users.stand(realm, "cesar", "id1"); err != nil {
+		t.Errorf("cesar should have been able to stand: %v", err)
+	}
+	if err := users.sit(realm, "xavi", "id3", 2); err != nil {
+		t.Errorf("xavi should have been able to sit: %v", err)
+	}
+	if err := users.stand(realm, "cesar", "id1"); err != errNotSeated {
+		t.Errorf("cesar should not have been seated, got %v", err)
+	}
+}
+
+func TestRosterAndPresence(t *testing.T) {
+	gameStates.reset()
+	users.reset()
+	MultiTabPolicy = TabMultiple
+	defer func() { MultiTabPolicy = TabTakeover }()
+	realm := toRealm(tableName)
+	users.add(realm, "messi", stSitting, "id1")
+	users.add(realm, "cesar", stWatching, "id2")
+	users.add(realm, "cesar", stWatching, "id3")
+	users.wantsToPlay(realm, "messi", "id1")
+	roster := users.roster(realm)
+	expected := []Presence{
+		{User: "cesar", State: "Watching", Conns: 2},
+		{User: "messi", State: "WantsToPlay", Conns: 1},
+	}
+	if len(roster) != len(expected) {
+		t.Fatalf("Unexpected roster %v", roster)
+	}
+	for i := range expected {
+		if roster[i] != expected[i] {
+			t.Errorf("Roster entry %v should have been %v", roster[i],
+				expected[i])
+		}
+	}
+	users.remove(realm, "messi", "id1")
+	p := users.presence(realm, "messi", PresenceLeave)
+	if p.Conns != 0 || p.Event != PresenceLeave {
+		t.Errorf("Unexpected presence after leaving: %v", p)
+	}
+}
+
+func TestLeavePresenceOnLastTab(t *testing.T) {
+	MultiTabPolicy = TabMultiple
+	defer func() { MultiTabPolicy = TabTakeover }()
+	realm := toRealm(tableName)
+	sender := newTable(realm)
+	MessageHandler.RealmJoin(realm, "cesar", "id1", true)
+	MessageHandler.RealmJoin(realm, "cesar", "id2", false)
+	MessageHandler.RealmLeave(realm, "cesar", "id2")
+	MessageHandler.RealmLeave(realm, "cesar", "id1")
+	var leaves []string
+	for i := 0; i < 100; i++ {
+		leaves = nil
+		for _, msg := range sender.messages(PresenceMT) {
+			if strings.Contains(msg, `"event":"leave"`) {
+				leaves = append(leaves, msg)
+			}
+		}
+		if len(leaves) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(leaves) != 1 ||
+		leaves[0] != `{"event":"leave","user":"cesar","state":"Watching",`+
+			`"conns":0}` {
+		t.Errorf("Expected a single leave on the last tab, got %v", leaves)
+	}
+}
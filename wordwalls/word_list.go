@@ -2,9 +2,23 @@ package wordwalls
 
 // Interface with the django aerolith word list API.
 
+import (
+	"errors"
+
+	"github.com/domino14/gosports/lexicon"
+)
+
+// ErrBadIndex means a word list points at questions it doesn't have.
+var ErrBadIndex = errors.New("list refers to questions it doesn't have")
+
 type Question struct {
 	Question string   `json:"q"`
 	Answers  []string `json:"a"`
+	// The alphagram's probability order within its length, if we know it.
+	Probability int `json:"p,omitempty"`
+	// Each answer's definition, hooks and lexicon symbols, once we've
+	// looked them up for a round.
+	Words []lexicon.FullAnswer `json:"w,omitempty"`
 }
 
 type WordList struct {
@@ -35,17 +49,58 @@ type WordList struct {
 	answerHash map[string]Answer
 }
 
+// NewWordList makes a temporary word list that asks all of the given
+// questions, in order.
+func NewWordList(lexicon string, name string, questions []Question) *WordList {
+	list := &WordList{
+		Lexicon:       lexicon,
+		Temporary:     true,
+		Name:          name,
+		OrigQuestions: questions,
+		NumQuestions:  len(questions),
+	}
+	list.Reset()
+	return list
+}
+
+// Reset starts the list over from the beginning, forgetting which
+// questions were missed.
+func (w *WordList) Reset() {
+	w.CurQuestions = make([]int, len(w.OrigQuestions))
+	for i := range w.OrigQuestions {
+		w.CurQuestions[i] = i
+	}
+	w.NumCurAlphagrams = len(w.CurQuestions)
+	w.QuestionIndex = 0
+	w.Missed = []int{}
+	w.NumMissed = 0
+	w.FirstMissed = []int{}
+	w.NumFirstMissed = 0
+	w.GoneThruOnce = false
+}
+
 // Gets the next set of questions for this word list, and advances the
 // pointer. Access to this word list is protected through the gamestates
 // holder structure, so we don't need a mutex here.
 func (w *WordList) nextSet(numQuestions int) []Question {
 	qmin := w.QuestionIndex
-	qmax := w.QuestionIndex + numQuestions
+	if qmin < 0 || qmin > len(w.CurQuestions) {
+		qmin = len(w.CurQuestions)
+	}
+	qmax := qmin + numQuestions
+	if qmax > len(w.CurQuestions) {
+		qmax = len(w.CurQuestions)
+	}
 	questions := w.CurQuestions[qmin:qmax]
-	w.QuestionIndex += numQuestions
+	w.QuestionIndex = qmax
 	return w.generateQuestions(questions)
 }
 
+// done is true once every question in the list has been asked.
+func (w *WordList) done() bool {
+	return w.QuestionIndex >= len(w.CurQuestions)
+}
+
 // Generate a set of Questions from the given questionIndices.
 func (w *WordList) generateQuestions(questionIndices []int) []Question {
 	questions := make([]Question, len(questionIndices))
@@ -56,13 +111,14 @@ func (w *WordList) generateQuestions(questionIndices []int) []Question {
 	return questions
 }
 
-// Generate answer hash and save it directly to w.answerHashes.
+// Generate answer hash and save it directly to w.answerHashes. The
+// words are normalized the same way guesses are.
 func (w *WordList) generateAnswerHash(questions []Question) {
 	w.answerHash = make(map[string]Answer)
 	for qidx, q := range questions {
 		for _, word := range q.Answers {
 			answer := Answer{Alphagram: q.Question, Idx: qidx}
-			w.answerHash[word] = answer
+			w.answerHash[lexicon.Normalize(word)] = answer
 		}
 	}
 }
@@ -71,3 +127,43 @@ func (w *WordList) generateAnswerHash(questions []Question) {
 func (w *WordList) saveProgress(wc WebolithCommunicator) {
 	syncWordList(wc, w)
 }
+
+// Mark a question as missed. If the list has not been gone through once
+// yet, it counts as first missed, too.
+func (w *WordList) markMissed(qidx int) {
+	if !containsIndex(w.Missed, qidx) {
+		w.Missed = append(w.Missed, qidx)
+		w.NumMissed = len(w.Missed)
+	}
+	if !w.GoneThruOnce && !containsIndex(w.FirstMissed, qidx) {
+		w.FirstMissed = append(w.FirstMissed, qidx)
+		w.NumFirstMissed = len(w.FirstMissed)
+	}
+}
+
+// CheckIndices makes sure every question the list points at is in the
+// list, so that it can be played. Lists that come from a file or a
+// user should be checked before they are.
+func (w *WordList) CheckIndices() error {
+	if w.QuestionIndex < 0 || w.QuestionIndex > len(w.CurQuestions) {
+		return ErrBadIndex
+	}
+	for _, indices := range [][]int{w.CurQuestions, w.Missed,
+		w.FirstMissed} {
+		for _, idx := range indices {
+			if idx < 0 || idx >= len(w.OrigQuestions) {
+				return ErrBadIndex
+			}
+		}
+	}
+	return nil
+}
+
+func containsIndex(indices []int, idx int) bool {
+	for _, i := range indices {
+		if i == idx {
+			return true
+		}
+	}
+	return false
+}
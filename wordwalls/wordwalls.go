@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/domino14/gosports/channels"
@@ -12,8 +13,12 @@ import (
 type GameType string
 
 const (
-	Challenge     GameType = "challenge"
-	Regular       GameType = "regular"
+	Challenge GameType = "challenge"
+	Regular   GameType = "regular"
+	// Everyone solves the same wall as a team.
+	Collaborative GameType = "collaborative"
+	// Everyone solves their own copy of the same wall.
+	Parallel      GameType = "parallel"
 	CountdownTime int      = 3
 )
 
@@ -23,6 +28,25 @@ const (
 	FailureNullWordList       = "NULL_WORD_LIST"
 	FailureQuestionInfo       = "QUESTION_INFO"
 	FailureGameGoing          = "GAME_GOING"
+	FailureNotSeatOwner       = "NOT_SEAT_OWNER"
+	FailureTableFull          = "TABLE_FULL"
+	FailureNotSeated          = "NOT_SEATED"
+	FailureNotHost            = "NOT_HOST"
+	FailureBadCommand         = "BAD_COMMAND"
+	FailureNotCountingDown    = "NOT_COUNTING_DOWN"
+	FailureListDone           = "LIST_DONE"
+	FailureBadScoring         = "BAD_SCORING"
+)
+
+// DefaultMaxSeats is used if the game options do not specify how many
+// players can sit at a table.
+const DefaultMaxSeats = 8
+
+// Reasons sent to a connection right before it gets kicked.
+const (
+	KickedTakeover = "You have opened this table in another window."
+	KickedByHost   = "The host has removed you from this table."
+	KickedLocked   = "This table is locked."
 )
 
 type GameOptions struct {
@@ -33,16 +57,113 @@ type GameOptions struct {
 	TimerSecs        int    `json:"timerSecs"`
 	QualifyForAward  bool   `json:"qualifyForAward"`
 	WordListID       int    `json:"_word_list_id"`
+	MaxSeats         int    `json:"maxSeats"`
+	// One of the Scoring* rules; per word if empty.
+	Scoring string `json:"scoring"`
+	// Take points off for every guess that isn't on the wall.
+	PenalizeWrong bool `json:"penalizeWrongGuesses"`
+}
+
+// The accessors below are safe to call before a table has any options,
+// e.g. when Webolith didn't send any.
+func (o *GameOptions) gameType() GameType {
+	if o == nil {
+		return ""
+	}
+	return GameType(o.GameType)
+}
+
+func (o *GameOptions) timerSecs() int {
+	if o == nil {
+		return 0
+	}
+	return o.TimerSecs
+}
+
+func (o *GameOptions) maxSeats() int {
+	if o == nil || o.MaxSeats <= 0 {
+		return DefaultMaxSeats
+	}
+	return o.MaxSeats
+}
+
+// ReadyState is broadcast whenever a user readies up or stops being
+// ready.
+type ReadyState struct {
+	User  string `json:"user"`
+	Ready bool   `json:"ready"`
+}
+
+// SeatChange is broadcast whenever a user sits, stands, or watches.
+type SeatChange struct {
+	User  string `json:"user"`
+	State string `json:"state"`
 }
 
 // CorrectAnswer encodes the index of the answer, the answer, the user
-// who got it, and the user's score.
+// who got it, its points, the user's score, and how many milliseconds into the
+// round it was found. In collaborative games, the team score is
+// included as well.
 type CorrectAnswer struct {
 	Answer    string `json:"answer"`
 	Alphagram string `json:"alphagram"`
 	User      string `json:"user"`
 	Idx       int    `json:"idx"`
+	// What this answer was worth under the table's scoring rule.
+	Points    int `json:"points"`
+	Score     int `json:"score"`
+	TeamScore int `json:"teamScore,omitempty"`
+	// How many answers are left to find. In parallel games this is
+	// for the user's own wall.
+	Remaining int   `json:"remaining"`
+	Elapsed   int64 `json:"elapsed"`
+}
+
+// Progress is broadcast in parallel games instead of the answer itself,
+// so that opponents only see how far along a player is.
+type Progress struct {
+	User      string `json:"user"`
 	Score     int    `json:"score"`
+	Remaining int    `json:"remaining"`
+}
+
+// Standing is a player's final place in a round. Tied players share a
+// rank.
+type Standing struct {
+	User  string `json:"user"`
+	Score int    `json:"score"`
+	Rank  int    `json:"rank"`
+}
+
+type byScore []Standing
+
+func (s byScore) Len() int      { return len(s) }
+func (s byScore) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byScore) Less(i, j int) bool {
+	if s[i].Score != s[j].Score {
+		return s[i].Score > s[j].Score
+	}
+	return s[i].User < s[j].User
+}
+
+// RoundSummary is broadcast when a round is over. Scores has each
+// player's score; in collaborative games, this is their contribution
+// to the team score.
+type RoundSummary struct {
+	GameType  GameType            `json:"gameType"`
+	Scores    map[string]int      `json:"scores"`
+	Solved    map[string][]string `json:"solved"`
+	TeamScore int                 `json:"teamScore,omitempty"`
+	Standings []Standing          `json:"standings"`
+	// Whether every answer on the wall was found. In parallel games,
+	// every player has to have cleared their wall.
+	Cleared bool           `json:"cleared"`
+	Fastest *FastestSolver `json:"fastest,omitempty"`
+	// How many guesses each player got wrong, repeated or had stolen.
+	Guesses map[string]GuessCounts `json:"guesses"`
+	// The round's questions with their hooks and definitions, for
+	// reviewing the answers.
+	Review []Question `json:"review"`
 }
 
 type wwMessageHandler struct {
@@ -71,6 +192,15 @@ const (
 	GameOverMT  channels.MessageType = "gameover"
 	ScoreMT     channels.MessageType = "score"
 	FailMT      channels.MessageType = "fail"
+	SeatMT      channels.MessageType = "seat"
+	PresenceMT  channels.MessageType = "presence"
+	RosterMT    channels.MessageType = "roster"
+	HostMT      channels.MessageType = "host"
+	SettingsMT  channels.MessageType = "settings"
+	ReadyMT     channels.MessageType = "ready"
+	CancelMT    channels.MessageType = "cancel"
+	ProgressMT  channels.MessageType = "progress"
+	SessionMT   channels.MessageType = "session"
 )
 
 func (s wwMessageSender) BroadcastMessage(realm channels.Realm,
@@ -79,6 +209,11 @@ func (s wwMessageSender) BroadcastMessage(realm channels.Realm,
 }
 func (s wwMessageSender) SendMessage(realm channels.Realm,
 	mt channels.MessageType, msg string, to string) {
+	channels.SendMessage(realm, mt, msg, to)
+}
+func (s wwMessageSender) Disconnect(realm channels.Realm, connId string,
+	reason string) {
+	channels.Disconnect(realm, connId, reason)
 }
 
 func (m wwMessageHandler) HandleMessage(msg channels.Message) {
@@ -87,14 +222,23 @@ func (m wwMessageHandler) HandleMessage(msg channels.Message) {
 	switch MessageType(msg.Mtype) {
 	case TableMT:
 		log.Println("[DEBUG] Got a table command.")
-		handleTableMessage(msg.Data, msg.Realm(), msg.From, m.webolith,
-			m.sender)
+		handleTableMessage(msg.Data, msg.Realm(), msg.From, msg.ConnID(),
+			m.webolith, m.sender)
 
 	case GuessMT:
 		handleGuess(msg.Data, msg.Realm(), msg.From, m.sender)
 	}
 }
 
+// ShouldBroadcast keeps guesses at parallel tables from being passed on
+// to the table; opponents only get to see each other's Progress.
+func (m wwMessageHandler) ShouldBroadcast(msg channels.Message) bool {
+	if MessageType(msg.Mtype) != GuessMT {
+		return true
+	}
+	return gameStates.gameTypeIfExists(msg.Realm()) != Parallel
+}
+
 var MessageHandler wwMessageHandler
 
 func init() {
@@ -124,40 +268,160 @@ func (m wwMessageHandler) RealmDeletion(table channels.Realm) {
 // firstUser is true if this is the first user to join.
 func (m wwMessageHandler) RealmJoin(table channels.Realm, user string,
 	connId string, firstUser bool) {
+	// We are being called from the hub, so we can't wait for it to
+	// deliver any of the messages below.
+	if err := users.checkEntry(table, user); err != nil {
+		reason := KickedLocked
+		if err == errBanned {
+			reason = KickedByHost
+		}
+		users.reject(connId)
+		go m.sender.Disconnect(table, connId, reason)
+		return
+	}
 	state := stWatching
 	if firstUser {
 		state = stSitting
 	}
-	users.add(table, user, state, connId)
+	kicked := users.add(table, user, state, connId)
+	newHost := users.claimHost(table, user)
+	presence := users.presence(table, user, PresenceJoin)
+	roster := users.roster(table)
+	go func() {
+		for _, oldId := range kicked {
+			m.sender.Disconnect(table, oldId, KickedTakeover)
+		}
+		sendRoster(table, user, roster, m.sender)
+		broadcastPresence(table, presence, m.sender)
+		if newHost {
+			m.sender.BroadcastMessage(table, HostMT, user)
+		}
+	}()
 }
 
 // On leaving a table, remove from the list of users for this table.
 func (m wwMessageHandler) RealmLeave(table channels.Realm, user string,
 	connId string) {
+	if users.wasRejected(connId) {
+		// Their join was never announced, so neither is their leaving.
+		return
+	}
 	users.remove(table, user, connId)
+	presence := users.presence(table, user, PresenceLeave)
+	newHost := users.passHost(table)
+	gone := presence.Conns == 0
+	var session *SessionSummary
+	if gone {
+		session = sessions.end(table, user)
+	}
 	users.RLock()
 	log.Printf("After RealmLeave: %v\n", users.userMap[table])
 	users.RUnlock()
+	go func() {
+		if gone {
+			// A user with other tabs open is still at the table.
+			broadcastPresence(table, presence, m.sender)
+			// The game state can't be locked in the hub's goroutine.
+			endStudy(table, user)
+		}
+		if session != nil {
+			broadcastSession(table, session, m.sender)
+		}
+		if newHost != "" {
+			m.sender.BroadcastMessage(table, HostMT, newHost)
+		}
+	}()
 }
 
 func handleTableMessage(data string, table channels.Realm, user string,
-	wc WebolithCommunicator, sender channels.SocketMessageSender) {
+	connId string, wc WebolithCommunicator,
+	sender channels.SocketMessageSender) {
 
-	switch data {
-	case "start":
-		handleStart(table, user, wc, sender)
+	fields := strings.Fields(data)
+	if len(fields) == 0 {
+		return
+	}
+	switch fields[0] {
+	case "start", "ready":
+		handleReady(true, table, user, connId, wc, sender)
+	case "unready":
+		handleReady(false, table, user, connId, wc, sender)
+	case "cancel":
+		handleCancel(table, user, connId, sender)
+	case "sit", "stand", "watch":
+		handleSeatChange(fields[0], table, user, connId, sender)
+	case "kick", "lock", "unlock", "set":
+		handleHostCommand(fields, table, user, connId, sender)
+	case "study", "reveal", "known", "unknown":
+		handleStudyCommand(fields, table, user, connId, sender)
+	case "load":
+		handleLoadShared(fields, table, user, connId, sender)
 	}
 }
 
-// handle a Start message. We set a lock when someone clicks Start
-// to prevent race conditions.
-func handleStart(table channels.Realm, user string, wc WebolithCommunicator,
+// handle a sit, stand, or watch command. Seats can't change while a
+// game is going.
+func handleSeatChange(cmd string, table channels.Realm, user string,
+	connId string, sender channels.SocketMessageSender) {
+	sendFail := func(errorCode string) {
+		sender.BroadcastMessage(table, FailMT, errorCode)
+	}
+	st := gameStates.getState(table)
+	st.Lock()
+	defer st.Unlock()
+
+	if st.options == nil {
+		log.Println("[ERROR] Settings for this table do not yet exist!")
+		sendFail(FailureSettingsDoNotExist)
+		return
+	}
+	if st.going == GameStarted {
+		log.Println("[DEBUG] Can't change seats while the game is going.")
+		sendFail(FailureGameGoing)
+		return
+	}
+	var err error
+	switch cmd {
+	case "sit":
+		err = users.sit(table, user, connId, st.options.maxSeats())
+	case "stand":
+		err = users.stand(table, user, connId)
+	case "watch":
+		err = users.watching(table, user, connId)
+	}
+	switch err {
+	case nil:
+	case errTableFull:
+		sendFail(FailureTableFull)
+		return
+	case errNotSeated:
+		sendFail(FailureNotSeated)
+		return
+	default:
+		sendFail(FailureNotSeatOwner)
+		return
+	}
+	newState, _ := users.userState(table, user)
+	msg, err := json.Marshal(SeatChange{User: user, State: newState.String()})
+	if err != nil {
+		log.Println("[ERROR] Marshalling seat change", err)
+		return
+	}
+	sender.BroadcastMessage(table, SeatMT, string(msg))
+	broadcastPresence(table, users.presence(table, user, PresenceState),
+		sender)
+}
+
+// handle a ready or unready message. We set a lock when someone
+// readies up to prevent race conditions. Once everyone seated is ready,
+// the game starts.
+func handleReady(ready bool, table channels.Realm, user string,
+	connId string, wc WebolithCommunicator,
 	sender channels.SocketMessageSender) {
-	log.Println("[DEBUG] In handleStart....")
+	log.Println("[DEBUG] In handleReady....")
 	sendFail := func(errorCode string) {
 		sender.BroadcastMessage(table, FailMT, errorCode)
 	}
-	// XXX: Set a lock for this table on start.
 	st := gameStates.getState(table)
 	st.Lock()
 	defer st.Unlock()
@@ -169,69 +433,231 @@ func handleStart(table channels.Realm, user string, wc WebolithCommunicator,
 		sendFail(FailureSettingsDoNotExist)
 		return
 	}
-	users.wantsToPlay(table, user)
-	if !users.allowStart(table) {
+	// Un-readying during the countdown cancels it.
+	canceling := st.going == GameCountingDown && !ready
+	if st.going != GameDone && !canceling {
+		log.Println("[DEBUG] This game is going or about to start.")
+		sendFail(FailureGameGoing)
+		return
+	}
+	var err error
+	if ready {
+		err = users.ready(table, user, connId)
+	} else {
+		err = users.unready(table, user, connId)
+	}
+	switch err {
+	case nil:
+	case errNotSeated:
+		sendFail(FailureNotSeated)
+		return
+	default:
+		sendFail(FailureNotSeatOwner)
+		return
+	}
+	// Only the player in the seat gets to cancel the countdown.
+	if canceling {
+		st.cancelCountdown()
+		sender.BroadcastMessage(table, CancelMT, user)
+	}
+	broadcastReady(table, user, ready, sender)
+	broadcastPresence(table, users.presence(table, user, PresenceState),
+		sender)
+	if !ready || !users.allowStart(table) {
 		log.Println("[DEBUG] Start not yet allowed.")
-		sendFail(FailureNotAllowed)
 		return
 	}
+	startGame(st, table, wc, sender)
+}
 
-	if st.going != GameDone {
-		log.Println("[DEBUG] This game is going or about to start.")
-		sendFail(FailureGameGoing)
+// handle a cancel message. Anyone seated can cancel the countdown,
+// which puts everyone back to not ready.
+func handleCancel(table channels.Realm, user string, connId string,
+	sender channels.SocketMessageSender) {
+	sendFail := func(errorCode string) {
+		sender.BroadcastMessage(table, FailMT, errorCode)
+	}
+	st := gameStates.getState(table)
+	st.Lock()
+	defer st.Unlock()
+
+	if st.going != GameCountingDown {
+		log.Println("[DEBUG] Nothing to cancel.")
+		sendFail(FailureNotCountingDown)
+		return
+	}
+	if state, ok := users.userState(table, user); !ok || !state.isSeated() {
+		sendFail(FailureNotSeated)
+		return
+	}
+	if users.seatConn(table, user) != connId {
+		sendFail(FailureNotSeatOwner)
+		return
+	}
+	st.cancelCountdown()
+	sender.BroadcastMessage(table, CancelMT, user)
+	for _, unready := range users.resetReady(table) {
+		broadcastReady(table, unready, false, sender)
+	}
+}
+
+func broadcastReady(table channels.Realm, user string, ready bool,
+	sender channels.SocketMessageSender) {
+	msg, err := json.Marshal(ReadyState{User: user, Ready: ready})
+	if err != nil {
+		log.Println("[ERROR] Marshalling ready state", err)
+		return
+	}
+	sender.BroadcastMessage(table, ReadyMT, string(msg))
+}
+
+// Start the game: get the word list and questions, and count down. The
+// state must be locked.
+func startGame(st *gameState, table channels.Realm, wc WebolithCommunicator,
+	sender channels.SocketMessageSender) {
+	sendFail := func(errorCode string) {
+		st.going = GameDone
+		sender.BroadcastMessage(table, FailMT, errorCode)
+	}
+	if _, err := st.options.scorer(); err != nil {
+		log.Println("[ERROR] Can't start game:", err)
+		sendFail(FailureBadScoring)
 		return
 	}
 	st.going = GameInitializing
-	wordList := getWordList(wc, st.options.WordListID)
+	var wordList *WordList
+	if st.loadedList != nil {
+		wordList = st.loadedList
+	} else if challenge := st.challenge(); challenge != nil {
+		wordList = challenge.wordList()
+	} else {
+		wordList = getWordList(wc, st.options.WordListID)
+	}
 	if wordList == nil {
 		log.Println("[ERROR] Got nil word list, error!")
 		sendFail(FailureNullWordList)
 		return
 	}
+	if wordList.done() {
+		// A loaded list is played until it runs out, and then the
+		// table goes back to its own list.
+		st.loadedList = nil
+		sendFail(FailureListDone)
+		return
+	}
 	st.setList(wordList)
 	qToSend := st.nextQuestionSet(st.options.QuestionsToPull)
+	if st.options.gameType() == Parallel {
+		st.setupPlayerWalls(users.players(table))
+	}
 	// Turn the raw alphagrams into full question objects.
-	fullQResponse, err := getFullQInfo(wc, qToSend, wordList.Lexicon)
+	fullQResponse, err := fullQuestionInfo(wc, qToSend, wordList.Lexicon)
 	if err != nil {
 		log.Println("[ERROR] Error getting full Q response!", err)
 		sendFail(FailureQuestionInfo)
 		return
 	}
 	log.Println("[DEBUG] Got full Q response:", string(fullQResponse))
-	countdown := time.NewTimer(time.Second * time.Duration(CountdownTime))
+	st.roundQuestions = withWordInfo(qToSend, fullQResponse)
+	st.round++
+	round := st.round
+	// Countdown before starting game.
+	// We should not accept guesses until the game has started.
+	countdown := time.AfterFunc(time.Second*time.Duration(CountdownTime),
+		func() {
+			beginRound(table, round, string(fullQResponse), sender)
+		})
 	st.setCountdownTimer(countdown)
 	st.going = GameCountingDown
 	sender.BroadcastMessage(table, CountdownMT, strconv.Itoa(CountdownTime))
-	// Countdown before starting game.
-	// We should not accept guesses until the game has started.
-	go handleGameTimer(table, countdown, string(fullQResponse), sender)
 	log.Println("[DEBUG] Leaving start, mutex should unlock.")
 }
 
-func handleGameTimer(table channels.Realm, countdown *time.Timer,
-	questionsToSend string, sender channels.SocketMessageSender) {
+// Called when the countdown is over. If the countdown was canceled
+// after the timer had already fired, the round will have changed, and
+// we do nothing.
+func beginRound(table channels.Realm, round int, questionsToSend string,
+	sender channels.SocketMessageSender) {
 
-	<-countdown.C
 	log.Println("[DEBUG] Finished counting down! About to send qs...")
 	st := gameStates.getState(table)
 	st.Lock()
 	defer st.Unlock()
+	if st.round != round || st.going != GameCountingDown {
+		log.Println("[DEBUG] Countdown was canceled.")
+		return
+	}
 	st.going = GameStarted
+	st.startedAt = time.Now()
 	sender.BroadcastMessage(table, QuestionsMT, questionsToSend)
 	sender.BroadcastMessage(table, TimerMT, strconv.Itoa(st.options.TimerSecs))
-	// Start another nested goroutine here for game over. This looks
-	// messy, but it seems easy enough to do.
-	gameOver := time.NewTimer(time.Second * time.Duration(st.options.TimerSecs))
+	gameOver := time.AfterFunc(
+		time.Second*time.Duration(st.options.TimerSecs), func() {
+			endRound(table, round, sender)
+		})
 	st.setGameTimer(gameOver)
-	go func() {
-		<-gameOver.C
-		log.Println("[DEBUG] This game is over!")
-		st := gameStates.getState(table)
-		st.Lock()
-		defer st.Unlock()
-		st.going = GameDone
-		sender.BroadcastMessage(table, GameOverMT, "")
-	}()
+}
+
+// Called when the game timer runs out.
+func endRound(table channels.Realm, round int,
+	sender channels.SocketMessageSender) {
+
+	log.Println("[DEBUG] This game is over!")
+	st := gameStates.getState(table)
+	st.Lock()
+	if st.round != round || st.going != GameStarted {
+		st.Unlock()
+		return
+	}
+	result := finishRound(st, table, sender)
+	st.Unlock()
+	recordRound(result)
+}
+
+// End the round early if the wall was cleared in a collaborative game,
+// or if everyone cleared their own wall in a parallel game.
+func endRoundIfCleared(table channels.Realm,
+	sender channels.SocketMessageSender) {
+
+	st := gameStates.getState(table)
+	st.Lock()
+	if st.going != GameStarted || st.options.gameType() == Regular ||
+		!st.cleared() {
+		st.Unlock()
+		return
+	}
+	log.Println("[DEBUG] The wall was cleared!")
+	st.cancelTimers()
+	result := finishRound(st, table, sender)
+	st.Unlock()
+	recordRound(result)
+}
+
+// Wrap up the round and send out the summary. The state must be locked.
+// The round's results are returned, to be recorded once the state is
+// unlocked.
+func finishRound(st *gameState, table channels.Realm,
+	sender channels.SocketMessageSender) *RoundResult {
+
+	st.going = GameDone
+	players := users.players(table)
+	now := time.Now()
+	var result *RoundResult
+	if st.list != nil {
+		result = st.roundResult(table, players, now)
+		result.challenge = st.challengeResults(players, now)
+		sessions.record(result)
+	}
+	msg, err := json.Marshal(st.summary(now))
+	if err != nil {
+		log.Println("[ERROR] Marshalling round summary", err)
+	}
+	sender.BroadcastMessage(table, GameOverMT, string(msg))
+	// Everyone has to ready up again for the next round.
+	for _, unready := range users.resetReady(table) {
+		broadcastReady(table, unready, false, sender)
+	}
+	return result
 }
 
 func handleGuess(data string, table channels.Realm, user string,
@@ -242,7 +668,10 @@ func handleGuess(data string, table channels.Realm, user string,
 		return
 	}
 
-	answer := gameStates.guess(data, table, user)
+	answer, feedback := gameStates.guess(data, table, user)
+	if feedback != nil {
+		sendGuessFeedback(table, user, feedback, sender)
+	}
 	if answer == nil {
 		return
 	}
@@ -251,7 +680,20 @@ func handleGuess(data string, table channels.Realm, user string,
 	if err != nil {
 		log.Println("[ERROR] Marshalling answer", answer, err)
 	}
-	sender.BroadcastMessage(table, ScoreMT, string(msg))
+	if gameStates.gameType(table) == Parallel {
+		// Only the guesser gets to see the word; everyone else just
+		// sees how far along they are.
+		sender.SendMessage(table, ScoreMT, string(msg), user)
+		progress, err := json.Marshal(Progress{User: user, Score: answer.Score,
+			Remaining: answer.Remaining})
+		if err != nil {
+			log.Println("[ERROR] Marshalling progress", err)
+		}
+		sender.BroadcastMessage(table, ProgressMT, string(progress))
+	} else {
+		sender.BroadcastMessage(table, ScoreMT, string(msg))
+	}
+	endRoundIfCleared(table, sender)
 }
 
 type Alphagram struct {
@@ -1,13 +1,16 @@
 package wordwalls
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/domino14/gosports/channels"
+	"github.com/domino14/gosports/lexicon"
 	"github.com/satori/go.uuid"
 )
 
@@ -49,6 +52,70 @@ func (s MockMessageSender) SendMessage(realm channels.Realm,
 		"message type: %s, to user: %s", msg, realm, mt, to)
 }
 
+func (s MockMessageSender) Disconnect(realm channels.Realm, connId string,
+	reason string) {
+	log.Printf("[INFO] Mock disconnect of connection: %s, realm: %s, "+
+		"reason: %s", connId, realm, reason)
+}
+
+type sentMessage struct {
+	mt  channels.MessageType
+	msg string
+	to  string
+}
+
+// RecordingMessageSender keeps every message it is asked to send, so
+// tests can check them.
+type RecordingMessageSender struct {
+	sync.Mutex
+	sent []sentMessage
+}
+
+func (s *RecordingMessageSender) BroadcastMessage(realm channels.Realm,
+	mt channels.MessageType, msg string) {
+	s.Lock()
+	defer s.Unlock()
+	s.sent = append(s.sent, sentMessage{mt: mt, msg: msg})
+}
+
+func (s *RecordingMessageSender) SendMessage(realm channels.Realm,
+	mt channels.MessageType, msg string, to string) {
+	s.Lock()
+	defer s.Unlock()
+	s.sent = append(s.sent, sentMessage{mt: mt, msg: msg, to: to})
+}
+
+func (s *RecordingMessageSender) Disconnect(realm channels.Realm,
+	connId string, reason string) {
+}
+
+// messages returns the messages of the given type that were sent.
+func (s *RecordingMessageSender) messages(mt channels.MessageType) []string {
+	s.Lock()
+	defer s.Unlock()
+	msgs := []string{}
+	for _, m := range s.sent {
+		if m.mt == mt {
+			msgs = append(msgs, m.msg)
+		}
+	}
+	return msgs
+}
+
+// recipients returns who each message of the given type was sent to,
+// or "" for broadcasts.
+func (s *RecordingMessageSender) recipients(mt channels.MessageType) []string {
+	s.Lock()
+	defer s.Unlock()
+	to := []string{}
+	for _, m := range s.sent {
+		if m.mt == mt {
+			to = append(to, m.to)
+		}
+	}
+	return to
+}
+
 func TestMockBehavior(t *testing.T) {
 	realm := toRealm(tablenum)
 	// Set mock so we don't connect to external API.
@@ -63,6 +130,19 @@ func TestMockBehavior(t *testing.T) {
 /////////////
 /// Helper functions.
 
+// newTable starts every test from an empty server with a single table,
+// using the mock Webolith. Everything sent to the table is recorded.
+func newTable(realm channels.Realm) *RecordingMessageSender {
+	gameStates.reset()
+	users.reset()
+	sessions.reset()
+	sender := &RecordingMessageSender{}
+	MessageHandler.webolith = &MockWebolithCommunicator{}
+	MessageHandler.sender = sender
+	MessageHandler.RealmCreation(realm)
+	return sender
+}
+
 func joinSitting(users []string, realm channels.Realm) {
 	doneCh := make(chan string, len(users))
 	for _, user := range users {
@@ -78,9 +158,20 @@ func joinSitting(users []string, realm channels.Realm) {
 	}
 }
 
-func requestStart(users []string, realm channels.Realm) { // All request start.
-	doneCh := make(chan string, len(users))
-	for _, user := range users {
+func tableCommand(cmd string, user string, realm channels.Realm) {
+	msg := channels.Message{
+		Data:  cmd,
+		Mtype: channels.MessageType(TableMT),
+		From:  user,
+	}
+	msg.SetRealm(realm)
+	msg.SetConnID(users.seatConn(realm, user))
+	MessageHandler.HandleMessage(msg)
+}
+
+func requestStart(userlist []string, realm channels.Realm) { // All request start.
+	doneCh := make(chan string, len(userlist))
+	for _, user := range userlist {
 		go func(user string) {
 			msg := channels.Message{
 				Data:  "start",
@@ -88,11 +179,12 @@ func requestStart(users []string, realm channels.Realm) { // All request start.
 				From:  user,
 			}
 			msg.SetRealm(realm)
+			msg.SetConnID(users.seatConn(realm, user))
 			MessageHandler.HandleMessage(msg)
 			doneCh <- user
 		}(user)
 	}
-	for i := 0; i < len(users); i++ {
+	for i := 0; i < len(userlist); i++ {
 		// Drain the channel.
 		log.Printf("[DEBUG] Draining %s\n", <-doneCh)
 	}
@@ -192,3 +284,254 @@ func TestSameUserStart(t *testing.T) {
 		t.Errorf("Score for cesar should have been 53.")
 	}
 }
+
+func TestSeatChanges(t *testing.T) {
+	realm := toRealm(tablenum)
+	sender := newTable(realm)
+	MessageHandler.RealmJoin(realm, "cesar", "id1", true)
+	MessageHandler.RealmJoin(realm, "messi", "id2", false)
+	tableCommand("sit", "messi", realm)
+	tableCommand("stand", "cesar", realm)
+	seats := sender.messages(SeatMT)
+	if len(seats) != 2 ||
+		seats[0] != `{"user":"messi","state":"Sitting"}` ||
+		seats[1] != `{"user":"cesar","state":"Watching"}` {
+		t.Errorf("Unexpected seat changes: %v", seats)
+	}
+	// No seat changes while a game is going.
+	gameStates.getState(realm).going = GameStarted
+	tableCommand("sit", "cesar", realm)
+	if len(sender.messages(SeatMT)) != 2 {
+		t.Errorf("Should not have been able to sit during a game")
+	}
+	fails := sender.messages(FailMT)
+	if len(fails) != 1 || fails[0] != FailureGameGoing {
+		t.Errorf("Unexpected failures: %v", fails)
+	}
+}
+
+func TestReadyAndCancel(t *testing.T) {
+	realm := toRealm(tablenum)
+	sender := newTable(realm)
+	joinSitting([]string{"cesar", "messi"}, realm)
+	tableCommand("ready", "cesar", realm)
+	if gameStates.getGameGoing(realm) != GameDone {
+		t.Fatalf("Game should not have started with one player ready")
+	}
+	tableCommand("ready", "messi", realm)
+	if gameStates.getGameGoing(realm) != GameCountingDown {
+		t.Fatalf("Game should be counting down")
+	}
+	tableCommand("cancel", "messi", realm)
+	if gameStates.getGameGoing(realm) != GameDone {
+		t.Fatalf("Countdown should have been canceled")
+	}
+	if gameStates.getState(realm).list.QuestionIndex != 0 {
+		t.Errorf("Questions should have been put back in the list")
+	}
+	time.Sleep(time.Second * (time.Duration(CountdownTime) + 1))
+	if gameStates.getGameGoing(realm) != GameDone {
+		t.Errorf("Canceled game should not have started")
+	}
+	if len(sender.messages(QuestionsMT)) != 0 {
+		t.Errorf("Questions should not have been sent")
+	}
+	ready := sender.messages(ReadyMT)
+	expected := []string{
+		`{"user":"cesar","ready":true}`,
+		`{"user":"messi","ready":true}`,
+		`{"user":"cesar","ready":false}`,
+		`{"user":"messi","ready":false}`,
+	}
+	if len(ready) != len(expected) {
+		t.Fatalf("Unexpected ready states: %v", ready)
+	}
+	for i := range expected {
+		if ready[i] != expected[i] {
+			t.Errorf("Ready state %v should have been %v", ready[i],
+				expected[i])
+		}
+	}
+	if len(sender.messages(CancelMT)) != 1 {
+		t.Errorf("Should have broadcast the cancel")
+	}
+}
+
+func TestOnlySeatOwnersCancelByUnreadying(t *testing.T) {
+	realm := toRealm(tablenum)
+	sender := newTable(realm)
+	joinSitting([]string{"cesar", "messi"}, realm)
+	MessageHandler.RealmJoin(realm, "xavi", "id3", false)
+	tableCommand("ready", "cesar", realm)
+	tableCommand("ready", "messi", realm)
+	if gameStates.getGameGoing(realm) != GameCountingDown {
+		t.Fatalf("Game should be counting down")
+	}
+	tableCommand("unready", "xavi", realm)
+	// messi, from a tab that doesn't own the seat.
+	msg := channels.Message{Data: "unready",
+		Mtype: channels.MessageType(TableMT), From: "messi"}
+	msg.SetRealm(realm)
+	msg.SetConnID("another-tab")
+	MessageHandler.HandleMessage(msg)
+	if gameStates.getGameGoing(realm) != GameCountingDown {
+		t.Errorf("Countdown should not have been canceled")
+	}
+	fails := sender.messages(FailMT)
+	if len(fails) != 2 || fails[0] != FailureNotSeated ||
+		fails[1] != FailureNotSeatOwner {
+		t.Errorf("Unexpected failures: %v", fails)
+	}
+	tableCommand("unready", "messi", realm)
+	if gameStates.getGameGoing(realm) != GameDone ||
+		len(sender.messages(CancelMT)) != 1 {
+		t.Errorf("The seat owner should have canceled the countdown")
+	}
+}
+
+func TestCollaborativeGame(t *testing.T) {
+	realm := toRealm(tablenum)
+	sender := newTable(realm)
+	gameStates.getState(realm).options.GameType = string(Collaborative)
+	userlist := []string{"cesar", "messi", "xavi", "iniesta"}
+	joinSitting(userlist, realm)
+	requestStart(userlist, realm)
+	guessWords(userlist, realm)
+
+	// Clearing the wall should end the game without waiting for the timer.
+	if gameStates.getGameGoing(realm) != GameDone {
+		t.Fatalf("Game should have ended once the wall was cleared")
+	}
+	gameOver := sender.messages(GameOverMT)
+	if len(gameOver) != 1 {
+		t.Fatalf("Should have sent one game over, sent %v", gameOver)
+	}
+	summary := &RoundSummary{}
+	if err := json.Unmarshal([]byte(gameOver[0]), summary); err != nil {
+		t.Fatal(err)
+	}
+	if !summary.Cleared || summary.TeamScore != 53 {
+		t.Errorf("Unexpected summary: %v", summary)
+	}
+	contributions := 0
+	for user, score := range summary.Scores {
+		if len(summary.Solved[user]) != score {
+			t.Errorf("%v solved %v but scored %v", user, summary.Solved[user],
+				score)
+		}
+		contributions += score
+	}
+	if contributions != 53 {
+		t.Errorf("Contributions should have added up to 53, got %v",
+			contributions)
+	}
+}
+
+func TestParallelGame(t *testing.T) {
+	realm := toRealm(tablenum)
+	sender := newTable(realm)
+	gameStates.getState(realm).options.GameType = string(Parallel)
+	userlist := []string{"cesar", "messi"}
+	joinSitting(userlist, realm)
+	MessageHandler.RealmJoin(realm, "xavi", "id3", false)
+	requestStart(userlist, realm)
+	// xavi is only watching, so their guesses should not count.
+	guessWords(append(userlist, "xavi"), realm)
+
+	scores := gameStates.scores(realm)
+	if scores["cesar"] != 53 || scores["messi"] != 53 || scores["xavi"] != 0 {
+		t.Errorf("Each player should have solved their own wall: %v", scores)
+	}
+	if gameStates.getGameGoing(realm) != GameDone {
+		t.Fatalf("Game should have ended once both walls were cleared")
+	}
+	// Nobody should see anyone else's words.
+	sender.Lock()
+	for _, m := range sender.sent {
+		if m.mt == ScoreMT && m.to == "" {
+			t.Errorf("Score should not have been broadcast: %v", m.msg)
+		}
+	}
+	sender.Unlock()
+	if len(sender.messages(ProgressMT)) != 106 {
+		t.Errorf("Should have broadcast progress for every solve")
+	}
+	summary := &RoundSummary{}
+	if err := json.Unmarshal([]byte(sender.messages(GameOverMT)[0]),
+		summary); err != nil {
+		t.Fatal(err)
+	}
+	expected := []Standing{
+		{User: "cesar", Score: 53, Rank: 1},
+		{User: "messi", Score: 53, Rank: 1},
+	}
+	if len(summary.Standings) != 2 || summary.Standings[0] != expected[0] ||
+		summary.Standings[1] != expected[1] || !summary.Cleared {
+		t.Errorf("Unexpected summary: %v", summary)
+	}
+}
+
+func TestLocalFullQuestions(t *testing.T) {
+	lexicon.Register(lexicon.New("LocalTest",
+		[]string{"BEQUESTS", "DECENTLY"}))
+	// The mock doesn't know about this lexicon, so this has to be local.
+	body, err := fullQuestionInfo(&MockWebolithCommunicator{},
+		[]Question{{Question: "BEEQSSTU"}, {Question: "CDEELNTY"}},
+		"LocalTest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var questions []lexicon.FullQuestion
+	if err := json.Unmarshal(body, &questions); err != nil {
+		t.Fatal(err)
+	}
+	if len(questions) != 2 || questions[1].Answers[0].Word != "DECENTLY" {
+		t.Errorf("Unexpected full questions %s", body)
+	}
+	alphas, err := LocalLexica{}.Alphagrams("LocalTest", 8)
+	if err != nil || len(alphas) != 2 || alphas[0].Question != "BEEQSSTU" {
+		t.Errorf("Unexpected alphagrams %v, %v", alphas, err)
+	}
+}
+
+func TestProbabilityList(t *testing.T) {
+	lexicon.Register(lexicon.New("ProbTest",
+		[]string{"BEQUESTS", "DECENTLY", "RETAINED", "JUKEBOXES"}))
+	list, err := ProbabilityList("ProbTest", 8, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.NumQuestions != 2 || list.OrigQuestions[0].Question != "ADEEINRT" ||
+		list.OrigQuestions[0].Probability != 1 ||
+		list.OrigQuestions[1].Probability != 2 {
+		t.Errorf("Unexpected list %v", list.OrigQuestions)
+	}
+	if _, err := ProbabilityList("NotLoaded", 8, 2); err == nil {
+		t.Errorf("Should not make a list from a lexicon that isn't loaded")
+	}
+}
+
+func TestWithWordInfo(t *testing.T) {
+	lex := lexicon.New("HookTest", []string{"RETAIN", "RETAINS", "QI"})
+	questions := []Question{
+		{Question: "AEINRT", Answers: []string{"RETAIN"}},
+		{Question: "AEINRST", Answers: []string{"RETAINS"}},
+	}
+	full, err := lex.FullQuestionsJSON([]string{"AEINRT", "AEINRST"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withInfo := withWordInfo(questions, full)
+	if len(withInfo) != 2 || withInfo[0].Words[0].BackHooks != "S" ||
+		!withInfo[1].Words[0].BackInner {
+		t.Errorf("Unexpected questions %v", withInfo)
+	}
+	if questions[0].Words != nil {
+		t.Errorf("The original questions should not change")
+	}
+	// Without info, the questions are still usable.
+	withInfo = withWordInfo(questions, []byte("not json"))
+	if len(withInfo) != 2 || withInfo[0].Words != nil {
+		t.Errorf("Unexpected questions %v", withInfo)
+	}
+}